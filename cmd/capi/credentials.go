@@ -0,0 +1,64 @@
+package capi
+
+import (
+	"errors"
+
+	"github.com/zerodi/cctl/internal/capix"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+const capmoxDeployment = "capmox-controller-manager"
+
+func credentialsCmd() *cobra.Command {
+	var (
+		kubeconfig string
+		namespace  string
+		name       string
+		restart    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "credentials",
+		Short: "Write or rotate the CAPMOX credentials secret from the proxmox.* settings",
+		Long: `Write or rotate the CAPMOX credentials secret in the management cluster.
+
+The secret (capmox-manager-credentials by default) receives the Proxmox API
+URL, token ID and secret from the proxmox.* settings. When its content
+changed, the CAPMOX controller is restarted to pick up the new credentials
+unless --restart=false is given.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vars := proxmoxVariables()
+			if len(vars) < 3 {
+				return errors.New("proxmox url, token id and token secret are required (see `cctl proxmox token create`)")
+			}
+			ctx := cmd.Context()
+			changed, err := capix.ApplySecret(ctx, kubeconfig, namespace, name, map[string]string{
+				"url":    vars["PROXMOX_URL"],
+				"token":  vars["PROXMOX_TOKEN"],
+				"secret": vars["PROXMOX_SECRET"],
+			}, map[string]string{"platform.ionos.com/secret-type": "proxmox-credentials"})
+			if err != nil {
+				return err
+			}
+			if !changed {
+				log.Info().Str("namespace", namespace).Str("secret", name).Msg("CAPMOX credentials already up to date")
+				return nil
+			}
+			log.Info().Str("namespace", namespace).Str("secret", name).Msg("CAPMOX credentials written")
+
+			if !restart {
+				return nil
+			}
+			log.Info().Str("namespace", namespace).Str("deployment", capmoxDeployment).Msg("Restarting CAPMOX controller")
+			return capix.RestartDeployment(ctx, kubeconfig, namespace, capmoxDeployment)
+		},
+	}
+
+	cmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Management cluster kubeconfig (default: current kubectl context)")
+	cmd.Flags().StringVar(&namespace, "secret-namespace", "capmox-system", "Namespace of the CAPMOX controller")
+	cmd.Flags().StringVar(&name, "secret-name", "capmox-manager-credentials", "Name of the credentials secret")
+	cmd.Flags().BoolVar(&restart, "restart", true, "Restart the CAPMOX controller when the credentials changed")
+	return cmd
+}
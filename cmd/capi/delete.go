@@ -0,0 +1,150 @@
+package capi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/zerodi/cctl/internal/capix"
+	"github.com/zerodi/cctl/internal/configx"
+	"github.com/zerodi/cctl/internal/executil"
+	"github.com/zerodi/cctl/internal/proxmox"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func deleteCmd() *cobra.Command {
+	var (
+		kubeconfig    string
+		timeout       time.Duration
+		keepArtifacts bool
+		yes           bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "delete",
+		Short: "Delete the workload cluster and wait until its machines are gone",
+		Long: `Delete the workload cluster and wait until its machines are gone.
+
+The Cluster object of --cluster-name is deleted in the management cluster and
+the command waits until all Machines and ProxmoxMachines of the cluster are
+gone. Proxmox is then checked for leftover VMs: VMs of the deleted
+ProxmoxMachines, VMs tagged with the cluster name and VMs named
+<cluster-name>-*. Finally the kubeconfig and talosconfig of the cluster are
+removed from the output directory unless --keep-artifacts is set.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			settings := configx.Cluster()
+			if !yes {
+				ok, err := executil.Confirm(cmd.InOrStdin(), cmd.ErrOrStderr(),
+					fmt.Sprintf("Delete workload cluster %s/%s?", settings.Namespace, settings.Name))
+				if err != nil {
+					return err
+				}
+				if !ok {
+					log.Info().Msg("Aborted; nothing deleted")
+					return nil
+				}
+			}
+
+			machines, err := capix.DeleteCluster(ctx, capix.WaitOptions{
+				Kubeconfig: kubeconfig,
+				Namespace:  settings.Namespace,
+				Cluster:    settings.Name,
+				Timeout:    timeout,
+			})
+			if err != nil {
+				return err
+			}
+
+			if err := checkLeftoverVMs(ctx, settings.Name, machines); err != nil {
+				return err
+			}
+			if keepArtifacts {
+				return nil
+			}
+			return removeArtifacts(settings.KubeconfigPath, settings.TalosconfigPath)
+		},
+	}
+
+	cmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Management cluster kubeconfig (default: current kubectl context)")
+	cmd.Flags().DurationVar(&timeout, "timeout", 30*time.Minute, "Maximum time to wait for the machines to be deleted")
+	cmd.Flags().BoolVar(&keepArtifacts, "keep-artifacts", false, "Keep the kubeconfig and talosconfig in the output directory")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Do not ask for confirmation")
+	return cmd
+}
+
+// checkLeftoverVMs fails if Proxmox still has VMs of the deleted cluster. The check is skipped
+// when no Proxmox API URL is configured.
+func checkLeftoverVMs(ctx context.Context, cluster string, machines []capix.MachineRef) error {
+	cfg := configx.Proxmox()
+	if cfg.URL == "" {
+		log.Warn().Msg("Proxmox URL not configured; skipping leftover VM check")
+		return nil
+	}
+	client, err := proxmox.New(cfg)
+	if err != nil {
+		return err
+	}
+	vms, err := client.ListVMs(ctx)
+	if err != nil {
+		return err
+	}
+
+	pools, err := configx.WorkerPools()
+	if err != nil {
+		return err
+	}
+	var leftovers []string
+	for _, vm := range leftoverVMs(vms, cluster, machines, capix.MachineNamePattern(cluster, pools)) {
+		leftovers = append(leftovers, fmt.Sprintf("%d (%s on %s)", vm.VMID, vm.Name, vm.Node))
+	}
+	if len(leftovers) > 0 {
+		return fmt.Errorf("cluster %s deleted but %d VM(s) remain in Proxmox: %s (see: proxmox vms list --orphans --delete-orphans)",
+			cluster, len(leftovers), strings.Join(leftovers, ", "))
+	}
+	log.Info().Str("cluster", cluster).Msg("No leftover VMs in Proxmox")
+	return nil
+}
+
+// leftoverVMs returns the non-template VMs that belonged to the cluster: those with a VMID recorded by
+// one of its ProxmoxMachines, tagged with the cluster name, or named exactly like one of its machines.
+// A VM of another cluster that merely shares the name prefix (prod-eu for prod) is not a leftover.
+func leftoverVMs(vms []proxmox.VM, cluster string, machines []capix.MachineRef, names *regexp.Regexp) []proxmox.VM {
+	vmids := make(map[int64]bool, len(machines))
+	for _, m := range machines {
+		if m.VMID != 0 {
+			vmids[m.VMID] = true
+		}
+	}
+	var out []proxmox.VM
+	for _, vm := range vms {
+		if vm.IsTemplate() {
+			continue
+		}
+		if vmids[vm.VMID] || vm.HasTag(cluster) || names.MatchString(vm.Name) {
+			out = append(out, vm)
+		}
+	}
+	return out
+}
+
+func removeArtifacts(paths ...string) error {
+	var errs []error
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, fmt.Errorf("remove %s: %w", path, err))
+			}
+			continue
+		}
+		log.Info().Str("path", path).Msg("Removed artifact")
+	}
+	return errors.Join(errs...)
+}
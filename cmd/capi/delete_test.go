@@ -0,0 +1,49 @@
+package capi
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/zerodi/cctl/internal/capix"
+	"github.com/zerodi/cctl/internal/proxmox"
+)
+
+func TestLeftoverVMs(t *testing.T) {
+	names := capix.MachineNamePattern("prod", []string{"workers"})
+	machines := []capix.MachineRef{
+		{Name: "prod-control-plane-abcde", VMID: 101},
+		{Name: "prod-workers-7d9f8-x2k4p"}, // VM not created yet
+	}
+	tests := []struct {
+		name string
+		vm   proxmox.VM
+		want bool
+	}{
+		{"recorded vmid", proxmox.VM{VMID: 101, Name: "renamed"}, true},
+		{"cluster tag", proxmox.VM{VMID: 102, Name: "something", Tags: "k8s;prod"}, true},
+		{"control plane name", proxmox.VM{VMID: 103, Name: "prod-control-plane-zzzzz"}, true},
+		{"worker name", proxmox.VM{VMID: 104, Name: "prod-workers-7d9f8-x2k4p"}, true},
+		{"template", proxmox.VM{VMID: 105, Name: "prod-control-plane-zzzzz", Template: 1}, false},
+		{"other cluster control plane", proxmox.VM{VMID: 201, Name: "prod-eu-control-plane-abcde"}, false},
+		{"other cluster worker", proxmox.VM{VMID: 202, Name: "prod-eu-workers-7d9f8-x2k4p", Tags: "prod-eu"}, false},
+		{"unknown pool", proxmox.VM{VMID: 203, Name: "prod-db-7d9f8-x2k4p"}, false},
+		{"unrelated", proxmox.VM{VMID: 204, Name: "gitlab"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := leftoverVMs([]proxmox.VM{tt.vm}, "prod", machines, names)
+			if (len(got) == 1) != tt.want {
+				t.Fatalf("leftoverVMs(%+v) = %v, want leftover %v", tt.vm, got, tt.want)
+			}
+		})
+	}
+
+	all := []proxmox.VM{{VMID: 101}, {VMID: 201, Name: "prod-eu-control-plane-abcde"}, {VMID: 104, Name: "prod-workers-7d9f8-x2k4p"}}
+	var ids []int64
+	for _, vm := range leftoverVMs(all, "prod", machines, names) {
+		ids = append(ids, vm.VMID)
+	}
+	if !slices.Equal(ids, []int64{101, 104}) {
+		t.Fatalf("leftoverVMs() = %v, want [101 104]", ids)
+	}
+}
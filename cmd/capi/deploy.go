@@ -4,9 +4,15 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package capi
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"time"
 
 	"github.com/zerodi/cctl/internal/capix"
+	"github.com/zerodi/cctl/internal/configx"
 
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -14,23 +20,126 @@ import (
 )
 
 func deployCmd() *cobra.Command {
+	var (
+		kubeconfig     string
+		output         string
+		forceConflicts bool
+		dryRun         string
+		prune          bool
+		pruneTimeout   time.Duration
+		wait           bool
+		timeout        time.Duration
+	)
+
 	cmd := &cobra.Command{
 		Use:   "deploy",
-		Short: "apply Cluster API manifests (kubectl apply)",
+		Short: "apply Cluster API manifests (server-side apply)",
+		Long: `Apply Cluster API manifests with server-side apply.
+
+With --dry-run (or --dry-run=server) the apply request is sent with
+dryRun=All, so defaulting and admission webhooks run but nothing is persisted.
+--dry-run=client only compares the manifests with the live objects, which
+works offline from the webhooks but can miss changes they would make. A dry
+run exits with code 2 when objects would change (see also: capi diff).
+
+Applied objects are recorded in the ConfigMap cctl-inventory-<cluster-name>
+in the manifest namespace. With --prune, recorded objects that are no longer
+in the manifests are deleted: MachineHealthChecks first, then
+MachineDeployments and the control plane, then the Cluster and its
+infrastructure, and templates last. Each group is deleted before the next.
+
+Without --file the manifest written by capi generate (cluster.manifestPath)
+is applied; run capi generate first.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			file := viper.GetString("capi.file")
+			file, err := manifestFile(viper.GetString("capi.file"))
+			if err != nil {
+				return err
+			}
 			ns := viper.GetString("capi.namespace")
-			if file == "" {
-				return fmt.Errorf("manifest path is required: --file")
+			if output != "text" && output != "json" {
+				return fmt.Errorf("unknown output format %q (expected text or json)", output)
+			}
+			switch dryRun {
+			case capix.DryRunNone, capix.DryRunClient, capix.DryRunServer:
+			default:
+				return fmt.Errorf("unknown dry-run mode %q (expected none, client or server)", dryRun)
+			}
+			settings := configx.Cluster()
+			log.Info().Str("file", file).Str("namespace", ns).Str("dryRun", dryRun).Msg("capi deploy")
+			results, err := capix.Apply(cmd.Context(), file, capix.ApplyOptions{
+				Kubeconfig:     kubeconfig,
+				Namespace:      ns,
+				ForceConflicts: forceConflicts,
+				DryRun:         dryRun,
+				Inventory:      settings.Name,
+				Prune:          prune,
+				PruneTimeout:   pruneTimeout,
+			})
+			if werr := writeApplyResults(cmd.OutOrStdout(), output, results); werr != nil && err == nil {
+				err = werr
+			}
+			if err != nil {
+				return err
+			}
+			if dryRun != capix.DryRunNone {
+				return pendingChanges(cmd, results)
 			}
-			log.Info().Str("file", file).Str("namespace", ns).Msg("capi deploy")
-			return capix.Apply(file, ns)
+			if !wait {
+				return nil
+			}
+
+			return capix.WaitForCluster(cmd.Context(), capix.WaitOptions{
+				Kubeconfig: kubeconfig,
+				Namespace:  settings.Namespace,
+				Cluster:    settings.Name,
+				Timeout:    timeout,
+			})
 		},
 	}
 
-	cmd.Flags().StringP("file", "f", "configs/capi/templates/cluster.yaml", "file or directory with manifests")
+	cmd.Flags().StringP("file", "f", "", "file or directory with manifests (default: cluster.manifestPath written by capi generate)")
 	cmd.Flags().String("namespace", "default", "namespace for manifests")
+	cmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Management cluster kubeconfig (default: current kubectl context)")
+	cmd.Flags().StringVarP(&output, "output", "o", "text", "Output format: text|json")
+	cmd.Flags().BoolVar(&forceConflicts, "force-conflicts", false, "Take over fields owned by other field managers")
+	cmd.Flags().StringVar(&dryRun, "dry-run", capix.DryRunNone, "Only report what would change: none|client|server (server when given without a value)")
+	cmd.Flags().Lookup("dry-run").NoOptDefVal = capix.DryRunServer
+	cmd.Flags().BoolVar(&prune, "prune", false, "Delete objects applied earlier that are no longer in the manifests")
+	cmd.Flags().DurationVar(&pruneTimeout, "prune-timeout", 10*time.Minute, "Maximum time to wait for each group of pruned objects")
+	cmd.Flags().BoolVar(&wait, "wait", false, "Wait for the workload cluster to become ready (see: capi wait)")
+	cmd.Flags().DurationVar(&timeout, "timeout", 30*time.Minute, "Maximum time to wait with --wait")
 	_ = viper.BindPFlag("capi.file", cmd.Flags().Lookup("file"))
 	_ = viper.BindPFlag("capi.namespace", cmd.Flags().Lookup("namespace"))
 	return cmd
 }
+
+// manifestFile returns the manifests to apply: file, or the manifest written by capi generate when
+// file is empty. It fails when they do not exist rather than falling back to the embedded examples.
+func manifestFile(file string) (string, error) {
+	if file == "" {
+		file = configx.Cluster().ManifestPath
+	}
+	if _, err := os.Stat(file); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("manifests %s not found (see: capi generate, or pass --file)", file)
+		}
+		return "", err
+	}
+	return file, nil
+}
+
+func writeApplyResults(w io.Writer, output string, results []capix.ApplyResult) error {
+	switch output {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	case "text":
+		for _, r := range results {
+			fmt.Fprintln(w, r.String())
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown output format %q (expected text or json)", output)
+	}
+}
@@ -0,0 +1,62 @@
+package capi
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/zerodi/cctl/internal/capix"
+	"github.com/zerodi/cctl/internal/configx"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"sigs.k8s.io/yaml"
+)
+
+func describeCmd() *cobra.Command {
+	var (
+		kubeconfig string
+		output     string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "describe",
+		Short: "Show the status tree of the workload cluster",
+		Long: `Show the status tree of the workload cluster (--cluster-name): the Cluster,
+its infrastructure, the control plane, the MachineDeployments and the Machines
+with their ProxmoxMachine, VMID, Proxmox node, Kubernetes node and IPs.
+
+Every object shows its Ready condition with severity and reason, like
+clusterctl describe cluster.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			settings := configx.Cluster()
+			root, err := capix.Describe(cmd.Context(), viper.GetString("capi.clusterctl_config"), kubeconfig, settings.Namespace, settings.Name)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			switch output {
+			case "tree", "":
+				return capix.WriteTree(out, root)
+			case "json":
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				return enc.Encode(root)
+			case "yaml":
+				raw, err := yaml.Marshal(root)
+				if err != nil {
+					return err
+				}
+				_, err = out.Write(raw)
+				return err
+			default:
+				return fmt.Errorf("unknown output format %q (expected tree, json or yaml)", output)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Management cluster kubeconfig (default: current kubectl context)")
+	cmd.Flags().StringVarP(&output, "output", "o", "tree", "Output format: tree|json|yaml")
+	return cmd
+}
@@ -0,0 +1,86 @@
+package capi
+
+import (
+	"fmt"
+
+	"github.com/zerodi/cctl/internal/capix"
+	"github.com/zerodi/cctl/internal/exitx"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// pendingChanges returns an exitx.ChangesError if any result would create or configure an object.
+// Usage and cobra's error line are silenced since this is an expected outcome.
+func pendingChanges(cmd *cobra.Command, results []capix.ApplyResult) error {
+	changed := 0
+	for _, r := range results {
+		if r.Action != capix.ActionUnchanged {
+			changed++
+		}
+	}
+	if changed == 0 {
+		return nil
+	}
+	cmd.SilenceUsage, cmd.SilenceErrors = true, true
+	return exitx.ChangesError{Changed: changed}
+}
+
+func diffCmd() *cobra.Command {
+	var (
+		file           string
+		namespace      string
+		kubeconfig     string
+		forceConflicts bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Show what capi deploy would change in the management cluster",
+		Long: `Show what capi deploy would change in the management cluster.
+
+Every manifest is sent as a server-side dry-run apply and the result is
+compared with the live object. Server-populated fields (status,
+managedFields, resourceVersion, uid, generation, creationTimestamp) are
+ignored. Differences are printed as a unified diff.
+
+Exit codes: 0 when nothing would change, 2 when there are changes, 1 on error.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Default to the deploy settings (capi.file / capi.namespace) unless overridden here.
+			if !cmd.Flags().Changed("file") {
+				file = viper.GetString("capi.file")
+			}
+			if !cmd.Flags().Changed("namespace") {
+				namespace = viper.GetString("capi.namespace")
+			}
+			path, err := manifestFile(file)
+			if err != nil {
+				return err
+			}
+
+			results, err := capix.Diff(cmd.Context(), path, capix.ApplyOptions{
+				Kubeconfig:     kubeconfig,
+				Namespace:      namespace,
+				ForceConflicts: forceConflicts,
+			})
+			applied := make([]capix.ApplyResult, 0, len(results))
+			for _, r := range results {
+				if r.Diff != "" {
+					fmt.Fprint(cmd.OutOrStdout(), r.Diff)
+				}
+				applied = append(applied, r.ApplyResult)
+			}
+			if err != nil {
+				return err
+			}
+			return pendingChanges(cmd, applied)
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", "", "file or directory with manifests (default: cluster.manifestPath written by capi generate)")
+	cmd.Flags().StringVar(&namespace, "namespace", "default", "namespace for manifests")
+	cmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Management cluster kubeconfig (default: current kubectl context)")
+	cmd.Flags().BoolVar(&forceConflicts, "force-conflicts", false, "Take over fields owned by other field managers")
+	return cmd
+}
@@ -0,0 +1,82 @@
+package capi
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/zerodi/cctl/internal/capix"
+	"github.com/zerodi/cctl/internal/configx"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func generateCmd() *cobra.Command {
+	var stdout bool
+
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Render the workload cluster manifests from cluster.spec",
+		Long: `Render the workload cluster manifests from the cluster.spec section of the
+cctl config: Cluster, ProxmoxCluster, ProxmoxMachineTemplates,
+TalosControlPlane, TalosConfigTemplates, MachineDeployments and
+MachineHealthChecks.
+
+Name and namespace come from --cluster-name and --namespace. The bundle is
+written to <out-dir>/cluster-<name>.yaml (cluster.manifestPath) or to stdout
+with --stdout. Example config:
+
+  cluster:
+    name: coffee-cluster
+    spec:
+      kubernetesVersion: v1.34.0
+      controlPlaneEndpoint:
+        host: 192.168.100.200
+      network:
+        ipv4Pool:
+          addresses: [192.168.100.210-192.168.100.219]
+          gateway: 192.168.100.1
+      proxmox:
+        sourceNode: pve
+        templateID: 900
+      controlPlane:
+        replicas: 3
+      workers:
+        - name: workers
+          replicas: 2
+          memoryMiB: 8192`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			settings := configx.Cluster()
+			spec, err := configx.LoadClusterSpec()
+			if err != nil {
+				return err
+			}
+
+			manifest, err := capix.Generate(settings, spec)
+			if err != nil {
+				return err
+			}
+			if stdout {
+				_, err := cmd.OutOrStdout().Write(manifest)
+				return err
+			}
+
+			if err := os.MkdirAll(filepath.Dir(settings.ManifestPath), 0o755); err != nil {
+				return fmt.Errorf("ensure output dir: %w", err)
+			}
+			if err := os.WriteFile(settings.ManifestPath, manifest, 0o644); err != nil {
+				return fmt.Errorf("write %s: %w", settings.ManifestPath, err)
+			}
+			log.Info().
+				Str("cluster", settings.Name).
+				Str("namespace", settings.Namespace).
+				Str("path", settings.ManifestPath).
+				Msg("Cluster manifests generated")
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&stdout, "stdout", false, "Print the manifests instead of writing them to the output directory")
+	return cmd
+}
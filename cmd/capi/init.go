@@ -4,7 +4,11 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package capi
 
 import (
+	"strings"
+
 	"github.com/zerodi/cctl/internal/capix"
+	"github.com/zerodi/cctl/internal/configx"
+	"github.com/zerodi/cctl/internal/proxmox"
 
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -15,6 +19,22 @@ func initCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "init",
 		Short: "initialize Cluster API providers via clusterctl",
+		Long: `Initialize Cluster API providers via clusterctl.
+
+Providers given without a version ("talos" rather than "talos:v0.6.7") are
+installed with the version pinned in capi.versions of the cctl config, e.g.
+
+  capi:
+    versions:
+      - cluster-api:v1.11.2
+      - bootstrap-talos:v0.6.7
+      - control-plane-talos:v0.5.8
+      - proxmox:v0.7.4
+
+A clusterctl label (bootstrap-talos, control-plane-talos) pins a single
+provider type; a plain name (proxmox) pins every provider type with that
+name. The Talos bootstrap and control plane providers are released with
+different versions, so pin them by label.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cfg := viper.GetString("capi.clusterctl_config")
 			core := viper.GetString("capi.core")
@@ -23,6 +43,15 @@ func initCmd() *cobra.Command {
 			infras := viper.GetStringSlice("capi.infrastructure")
 			kubeconf := viper.GetString("capi.kubeconfig")
 
+			versions, err := configx.ProviderVersions()
+			if err != nil {
+				return err
+			}
+			var vars map[string]string
+			if hasProvider(infras, "proxmox") {
+				vars = proxmoxVariables()
+			}
+
 			log.Info().
 				Str("clusterctl", cfg).
 				Str("core", core).
@@ -31,11 +60,20 @@ func initCmd() *cobra.Command {
 				Strs("infrastructure", infras).
 				Msg("capi init")
 
-			return capix.Init(cfg, core, boots, cps, infras, kubeconf)
+			return capix.Init(cmd.Context(), capix.InitOptions{
+				ClusterctlConfig: cfg,
+				Kubeconfig:       kubeconf,
+				Core:             core,
+				Bootstrap:        boots,
+				ControlPlane:     cps,
+				Infrastructure:   infras,
+				Versions:         versions,
+				Variables:        vars,
+			})
 		},
 	}
 
-	cmd.Flags().String("clusterctl-config", "configs/capi/clusterctl.yaml", "path to clusterctl.yaml")
+	cmd.Flags().String("clusterctl-config", "", "path to clusterctl.yaml (default: $HOME/.cluster-api/clusterctl.yaml)")
 	cmd.Flags().String("core", "cluster-api", "CoreProvider (usually 'cluster-api')")
 	cmd.Flags().StringSlice("bootstrap", []string{"kubeadm"}, "BootstrapProviders (comma separated)")
 	cmd.Flags().StringSlice("control-plane", []string{"kubeadm"}, "ControlPlaneProviders (comma separated)")
@@ -50,3 +88,32 @@ func initCmd() *cobra.Command {
 	_ = viper.BindPFlag("capi.kubeconfig", cmd.Flags().Lookup("kubeconfig"))
 	return cmd
 }
+
+// hasProvider reports whether name is among providers, which may carry a version ("proxmox:v0.7.4").
+func hasProvider(providers []string, name string) bool {
+	for _, p := range providers {
+		if strings.EqualFold(strings.SplitN(p, ":", 2)[0], name) {
+			return true
+		}
+	}
+	return false
+}
+
+// proxmoxVariables maps the configured Proxmox credentials to the clusterctl variables
+// expected by the CAPMOX provider components. Unset values are left to clusterctl.
+func proxmoxVariables() map[string]string {
+	vars := map[string]string{}
+	if url := viper.GetString("proxmox.url"); url != "" {
+		vars["PROXMOX_URL"] = proxmox.APIURL(url)
+	}
+	if token := viper.GetString("proxmox.tokenID"); token != "" {
+		vars["PROXMOX_TOKEN"] = token
+	}
+	if secret := viper.GetString("proxmox.tokenSecret"); secret != "" {
+		vars["PROXMOX_SECRET"] = secret
+	}
+	if len(vars) < 3 {
+		log.Warn().Msg("Proxmox URL or API token not configured; clusterctl falls back to PROXMOX_* variables")
+	}
+	return vars
+}
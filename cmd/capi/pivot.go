@@ -0,0 +1,102 @@
+package capi
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/zerodi/cctl/internal/capix"
+	"github.com/zerodi/cctl/internal/configx"
+	"github.com/zerodi/cctl/internal/kindx"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func pivotCmd() *cobra.Command {
+	var (
+		kubeconfig         string
+		workloadKubeconfig string
+		reverse            bool
+		kindDown           bool
+		kindName           string
+		timeout            time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "pivot",
+		Short: "Move Cluster API management from the bootstrap cluster into the workload cluster",
+		Long: `Move Cluster API management from the bootstrap (kind) cluster into the
+workload cluster, making it self-managing.
+
+The providers installed in the bootstrap cluster are installed in the
+workload cluster with the same versions (see: capi init) and waited for.
+clusterctl move then transfers the Cluster API objects of the namespace, and
+the Cluster, machines and infrastructure objects are counted in the workload
+cluster to verify the move. With --kind-down the bootstrap cluster is deleted
+afterwards.
+
+The workload kubeconfig defaults to the one written by secrets get-kubeconfig.
+With --reverse the objects are moved back from the workload cluster into the
+bootstrap cluster, e.g. to recover with a fresh kind cluster.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			settings := configx.Cluster()
+			if workloadKubeconfig == "" {
+				workloadKubeconfig = settings.KubeconfigPath
+			}
+			if _, err := os.Stat(workloadKubeconfig); err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return fmt.Errorf("workload kubeconfig %s not found (see: secrets get-kubeconfig)", workloadKubeconfig)
+				}
+				return err
+			}
+			if reverse && kindDown {
+				return fmt.Errorf("--kind-down cannot be combined with --reverse")
+			}
+			if kindName == "" {
+				kindName = viper.GetString("kind.name")
+			}
+
+			opts := capix.PivotOptions{
+				From:      kubeconfig,
+				To:        workloadKubeconfig,
+				Namespace: settings.Namespace,
+				Cluster:   settings.Name,
+				Init: capix.InitOptions{
+					ClusterctlConfig: viper.GetString("capi.clusterctl_config"),
+					Variables:        proxmoxVariables(),
+					WaitTimeout:      timeout,
+				},
+			}
+			if reverse {
+				opts.From, opts.To = opts.To, opts.From
+			}
+
+			log.Info().
+				Str("cluster", opts.Cluster).
+				Str("from", opts.From).
+				Str("to", opts.To).
+				Bool("reverse", reverse).
+				Msg("capi pivot")
+			if err := capix.Pivot(cmd.Context(), opts); err != nil {
+				return err
+			}
+			if !kindDown {
+				return nil
+			}
+			log.Info().Str("name", kindName).Msg("deleting kind cluster")
+			return kindx.Delete(kindName)
+		},
+	}
+
+	cmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Bootstrap (kind) cluster kubeconfig (default: $KUBECONFIG or ~/.kube/config)")
+	cmd.Flags().StringVar(&workloadKubeconfig, "workload-kubeconfig", "", "Workload cluster kubeconfig (default: --kubeconfig-path)")
+	cmd.Flags().BoolVar(&reverse, "reverse", false, "Move the objects from the workload cluster back into the bootstrap cluster")
+	cmd.Flags().BoolVar(&kindDown, "kind-down", false, "Delete the kind bootstrap cluster after a successful pivot")
+	cmd.Flags().StringVar(&kindName, "kind-name", "", "kind cluster name for --kind-down (default: kind.name or dev)")
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Minute, "Maximum time to wait for each provider in the target cluster")
+	return cmd
+}
@@ -0,0 +1,99 @@
+package capi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/zerodi/cctl/internal/capix"
+	"github.com/zerodi/cctl/internal/configx"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func providersCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "providers",
+		Short: "Inspect the Cluster API providers of the management cluster",
+	}
+	cmd.AddCommand(providersListCmd())
+	return cmd
+}
+
+func providersListCmd() *cobra.Command {
+	var (
+		kubeconfig string
+		output     string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List installed providers with their current, pinned and available versions",
+		Long: `List installed providers with their current, pinned and available versions.
+
+AVAILABLE is the latest release for the newest contract supported by
+clusterctl (see: capi upgrade plan); PINNED is the version from capi.versions.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts, err := upgradeOptions(kubeconfig)
+			if err != nil {
+				return err
+			}
+			items, err := capix.ProviderStatuses(cmd.Context(), opts)
+			if err != nil {
+				return err
+			}
+
+			switch output {
+			case "json":
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(items)
+			case "table", "":
+				return writeProvidersTable(cmd.OutOrStdout(), items)
+			default:
+				return fmt.Errorf("unknown output format %q (expected table or json)", output)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Management cluster kubeconfig (default: current kubectl context)")
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format: table|json")
+	return cmd
+}
+
+// upgradeOptions collects the clusterctl config, kubeconfig and pinned versions of the cctl config.
+func upgradeOptions(kubeconfig string) (capix.UpgradeOptions, error) {
+	versions, err := configx.ProviderVersions()
+	if err != nil {
+		return capix.UpgradeOptions{}, err
+	}
+	return capix.UpgradeOptions{
+		ClusterctlConfig: viper.GetString("capi.clusterctl_config"),
+		Kubeconfig:       kubeconfig,
+		Versions:         versions,
+	}, nil
+}
+
+func writeProvidersTable(w io.Writer, items []capix.UpgradeItem) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tNAME\tTYPE\tVERSION\tPINNED\tAVAILABLE")
+	for _, item := range items {
+		next := item.Next
+		if next == "" {
+			next = "up to date"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			item.Namespace, item.ManifestLabel(), item.Type, item.Version, orDash(item.Pinned), next)
+	}
+	return tw.Flush()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -11,6 +11,17 @@ import (
 func New() *cobra.Command {
 	cmd := &cobra.Command{Use: "capi", Short: "Commands for Cluster API"}
 	cmd.AddCommand(initCmd())
+	cmd.AddCommand(generateCmd())
 	cmd.AddCommand(deployCmd())
+	cmd.AddCommand(diffCmd())
+	cmd.AddCommand(waitCmd())
+	cmd.AddCommand(deleteCmd())
+	cmd.AddCommand(pivotCmd())
+	cmd.AddCommand(providersCmd())
+	cmd.AddCommand(upgradeCmd())
+	cmd.AddCommand(upgradeK8sCmd())
+	cmd.AddCommand(scaleCmd())
+	cmd.AddCommand(describeCmd())
+	cmd.AddCommand(credentialsCmd())
 	return cmd
 }
@@ -0,0 +1,65 @@
+package capi
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/zerodi/cctl/internal/capix"
+	"github.com/zerodi/cctl/internal/configx"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func scaleCmd() *cobra.Command {
+	var (
+		kubeconfig   string
+		controlPlane int64
+		workers      map[string]int64
+		timeout      time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "scale",
+		Short: "Scale the control plane and worker pools of the workload cluster",
+		Long: `Scale the control plane and worker pools of the workload cluster.
+
+--control-plane sets the TalosControlPlane replicas, which must be odd for
+etcd quorum. --workers sets the replicas of a MachineDeployment, named with or
+without the cluster name prefix. Before scaling up, the IPv4 pool of the
+ProxmoxCluster is checked for enough addresses not used by a machine yet.
+
+The cluster is then waited for like capi deploy --wait: machine phase changes
+are logged until every machine is ready. The replicas are patched with the
+field manager cctl-scale, so a later capi deploy of manifests with other
+counts reports a conflict; update the manifests (or cluster.spec) to match.`,
+		Example: `  cctl capi scale --control-plane 3
+  cctl capi scale --workers workers=4
+  cctl capi scale --workers coffee-cluster-workers=2,gpu=1`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := capix.ScaleRequest{Workers: workers}
+			if cmd.Flags().Changed("control-plane") {
+				req.ControlPlane = &controlPlane
+			}
+			if req.ControlPlane == nil && len(req.Workers) == 0 {
+				return fmt.Errorf("nothing to scale: pass --control-plane and/or --workers")
+			}
+
+			settings := configx.Cluster()
+			log.Info().Str("cluster", settings.Name).Msg("capi scale")
+			return capix.Scale(cmd.Context(), capix.WaitOptions{
+				Kubeconfig: kubeconfig,
+				Namespace:  settings.Namespace,
+				Cluster:    settings.Name,
+				Timeout:    timeout,
+			}, req)
+		},
+	}
+
+	cmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Management cluster kubeconfig (default: current kubectl context)")
+	cmd.Flags().Int64Var(&controlPlane, "control-plane", 0, "Control plane replicas (odd)")
+	cmd.Flags().StringToInt64Var(&workers, "workers", nil, "MachineDeployment replicas (deployment=N, comma separated or repeatable)")
+	cmd.Flags().DurationVar(&timeout, "timeout", 30*time.Minute, "Maximum time to wait for the cluster")
+	return cmd
+}
@@ -0,0 +1,121 @@
+package capi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/zerodi/cctl/internal/capix"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func upgradeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Plan and apply Cluster API provider upgrades",
+	}
+	cmd.AddCommand(upgradePlanCmd())
+	cmd.AddCommand(upgradeApplyCmd())
+	return cmd
+}
+
+func upgradePlanCmd() *cobra.Command {
+	var (
+		kubeconfig string
+		output     string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Show the provider upgrades available per Cluster API contract",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts, err := upgradeOptions(kubeconfig)
+			if err != nil {
+				return err
+			}
+			plans, err := capix.PlanUpgrade(cmd.Context(), opts)
+			if err != nil {
+				return err
+			}
+
+			switch output {
+			case "json":
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(plans)
+			case "table", "":
+				return writeUpgradePlans(cmd.OutOrStdout(), plans)
+			default:
+				return fmt.Errorf("unknown output format %q (expected table or json)", output)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Management cluster kubeconfig (default: current kubectl context)")
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format: table|json")
+	return cmd
+}
+
+func upgradeApplyCmd() *cobra.Command {
+	var (
+		kubeconfig string
+		contract   string
+		timeout    time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Upgrade providers to the pinned versions or to the latest release of a contract",
+		Long: `Upgrade the providers of the management cluster.
+
+Without --contract, every installed provider whose version differs from the
+one pinned in capi.versions is upgraded (or downgraded) to the pinned version;
+providers without a pin are left alone. With --contract, all providers are
+upgraded to the latest release of that contract and pins are ignored.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts, err := upgradeOptions(kubeconfig)
+			if err != nil {
+				return err
+			}
+			if contract == "" && len(opts.Versions) == 0 {
+				return fmt.Errorf("nothing to upgrade: pass --contract or pin provider versions in capi.versions")
+			}
+			opts.Contract = contract
+			opts.WaitTimeout = timeout
+
+			log.Info().Str("contract", contract).Msg("capi upgrade apply")
+			return capix.ApplyUpgrade(cmd.Context(), opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Management cluster kubeconfig (default: current kubectl context)")
+	cmd.Flags().StringVar(&contract, "contract", "", "Upgrade all providers to the latest release of this contract (e.g. v1beta1)")
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Minute, "Maximum time to wait for each upgraded provider")
+	return cmd
+}
+
+func writeUpgradePlans(w io.Writer, plans []capix.UpgradePlan) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for i, plan := range plans {
+		if i > 0 {
+			fmt.Fprintln(tw)
+		}
+		fmt.Fprintf(tw, "Contract %s:\n", plan.Contract)
+		fmt.Fprintln(tw, "NAME\tNAMESPACE\tTYPE\tCURRENT VERSION\tNEXT VERSION\tPINNED")
+		for _, item := range plan.Providers {
+			next := item.Next
+			if next == "" {
+				next = "Already up to date"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				item.ManifestLabel(), item.Namespace, item.Type, item.Version, next, orDash(item.Pinned))
+		}
+	}
+	return tw.Flush()
+}
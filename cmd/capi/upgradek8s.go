@@ -0,0 +1,77 @@
+package capi
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zerodi/cctl/internal/capix"
+	"github.com/zerodi/cctl/internal/configx"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func upgradeK8sCmd() *cobra.Command {
+	var (
+		kubeconfig string
+		to         string
+		timeout    time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "upgrade-k8s",
+		Short: "Roll the workload cluster to a new Kubernetes version",
+		Long: `Roll the workload cluster to a new Kubernetes version.
+
+The version skew is validated first: the control plane moves at most one minor
+version, and MachineDeployments must stay within three minor versions of it.
+The TalosControlPlane is patched and its rollout awaited, then every
+MachineDeployment in turn. Machine phase changes and rollout progress are
+logged. The upgrade stops as soon as a MachineHealthCheck remediates a machine;
+objects not reached yet keep their old version.
+
+The version fields are patched with the field manager cctl-upgrade, so a later
+capi deploy of manifests with the old version reports a conflict. When the
+config file defines cluster.spec, its kubernetesVersion is updated so that
+capi generate renders the new version.`,
+		Example: `  cctl capi upgrade-k8s --to v1.35.1`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if to == "" {
+				return fmt.Errorf("target version is required: --to")
+			}
+			if !strings.HasPrefix(to, "v") {
+				to = "v" + to
+			}
+			settings := configx.Cluster()
+			log.Info().Str("cluster", settings.Name).Str("to", to).Msg("capi upgrade-k8s")
+			err := capix.UpgradeKubernetes(cmd.Context(), capix.WaitOptions{
+				Kubeconfig: kubeconfig,
+				Namespace:  settings.Namespace,
+				Cluster:    settings.Name,
+				Timeout:    timeout,
+			}, to)
+			if err != nil {
+				return err
+			}
+
+			if viper.ConfigFileUsed() == "" || !viper.IsSet("cluster.spec") {
+				log.Info().Msg("Update spec.version in your manifests to match the cluster")
+				return nil
+			}
+			path, err := configx.Persist(map[string]any{"cluster.spec.kubernetesVersion": to})
+			if err != nil {
+				return err
+			}
+			log.Info().Str("config", path).Str("version", to).Msg("Updated cluster.spec.kubernetesVersion")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Management cluster kubeconfig (default: current kubectl context)")
+	cmd.Flags().StringVar(&to, "to", "", "Target Kubernetes version (e.g. v1.35.1)")
+	cmd.Flags().DurationVar(&timeout, "timeout", 30*time.Minute, "Maximum time to wait for each rollout")
+	return cmd
+}
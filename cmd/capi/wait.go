@@ -0,0 +1,42 @@
+package capi
+
+import (
+	"time"
+
+	"github.com/zerodi/cctl/internal/capix"
+	"github.com/zerodi/cctl/internal/configx"
+
+	"github.com/spf13/cobra"
+)
+
+func waitCmd() *cobra.Command {
+	var (
+		kubeconfig string
+		timeout    time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "wait",
+		Short: "Wait until the workload cluster is provisioned and all machines are ready",
+		Long: `Wait until the workload cluster is provisioned and all machines are ready.
+
+The Cluster, TalosControlPlane, MachineDeployments and Machines of
+--cluster-name are polled in the management cluster; machine phase changes
+are logged as they happen. When --timeout expires the command fails with a
+summary of the conditions that are not yet satisfied.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			settings := configx.Cluster()
+			return capix.WaitForCluster(cmd.Context(), capix.WaitOptions{
+				Kubeconfig: kubeconfig,
+				Namespace:  settings.Namespace,
+				Cluster:    settings.Name,
+				Timeout:    timeout,
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Management cluster kubeconfig (default: current kubectl context)")
+	cmd.Flags().DurationVar(&timeout, "timeout", 30*time.Minute, "Maximum time to wait for the cluster")
+	return cmd
+}
@@ -24,7 +24,7 @@ func resetCmd() *cobra.Command {
 	}
 
 	cmd.Flags().String("name", "dev", "kind cluster name")
-	cmd.Flags().String("config", "configs/kind.yaml", "path to kind config")
+	cmd.Flags().String("config", "config/kind.yaml", "path to kind config")
 	_ = viper.BindPFlag("kind.name", cmd.Flags().Lookup("name"))
 	_ = viper.BindPFlag("kind.config", cmd.Flags().Lookup("config"))
 	return cmd
@@ -0,0 +1,108 @@
+package proxmox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/zerodi/cctl/internal/proxmox"
+
+	"github.com/spf13/cobra"
+)
+
+func imagesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "images",
+		Short: "Inspect and prune images on Proxmox storage",
+	}
+	cmd.AddCommand(imagesListCmd())
+	cmd.AddCommand(imagesDeleteCmd())
+	return cmd
+}
+
+func imagesListCmd() *cobra.Command {
+	var (
+		storage string
+		content string
+		output  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List storage content (ISOs by default)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := clientFromConfig()
+			if err != nil {
+				return err
+			}
+
+			items, err := client.ListStorageContent(cmd.Context(), storage, content)
+			if err != nil {
+				return err
+			}
+
+			switch output {
+			case "json":
+				return writeJSON(cmd.OutOrStdout(), items)
+			case "table", "":
+				return writeImagesTable(cmd.OutOrStdout(), items)
+			default:
+				return fmt.Errorf("unknown output format %q (expected table or json)", output)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&storage, "storage", "", "Proxmox storage to list (default: --iso-storage)")
+	cmd.Flags().StringVar(&content, "content", "iso", "Content type filter (iso, images, vztmpl, ...; empty for all)")
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format: table|json")
+	return cmd
+}
+
+func imagesDeleteCmd() *cobra.Command {
+	var storage string
+
+	cmd := &cobra.Command{
+		Use:   "delete <volid|file>...",
+		Short: "Delete images from Proxmox storage",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := clientFromConfig()
+			if err != nil {
+				return err
+			}
+
+			var errs []error
+			for _, volume := range args {
+				if err := client.DeleteStorageContent(cmd.Context(), storage, volume); err != nil {
+					errs = append(errs, fmt.Errorf("delete %s: %w", volume, err))
+				}
+			}
+			return errors.Join(errs...)
+		},
+	}
+
+	cmd.Flags().StringVar(&storage, "storage", "", "Proxmox storage holding the images (default: --iso-storage)")
+	return cmd
+}
+
+func writeImagesTable(w io.Writer, items []proxmox.StorageContent) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VOLID\tCONTENT\tFORMAT\tSIZE\tCREATED")
+	for _, item := range items {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			item.VolID,
+			item.Content,
+			item.Format,
+			proxmox.FormatBytes(item.Size),
+			item.Created().Format("2006-01-02 15:04"))
+	}
+	return tw.Flush()
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
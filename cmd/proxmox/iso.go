@@ -3,29 +3,66 @@ package proxmox
 import (
 	"errors"
 
+	"github.com/zerodi/cctl/internal/proxmox"
+
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 func getTalosImageCmd() *cobra.Command {
-	var version string
+	var (
+		version           string
+		checksum          string
+		checksumAlgorithm string
+		force             bool
+	)
 
 	cmd := &cobra.Command{
 		Use:   "get-talos-image",
-		Short: "Download Talos ISO for the given version and upload to Proxmox storage",
+		Short: "Import the Talos ISO for the given version into Proxmox storage",
+		Long: `Import the Talos ISO for the given version into Proxmox storage.
+
+With --mode=download-url (default) the Proxmox node fetches the image from
+factory.talos.dev itself. Use --mode=relay for nodes without internet access:
+the ISO is downloaded locally and uploaded to Proxmox.
+
+The transfer is skipped when an ISO with the same name and size already exists
+on the storage, unless --force is given. Proxmox cannot checksum a stored ISO,
+so with --checksum an existing copy is always replaced by a verified transfer.
+
+A replacement is first transferred as <name>.cctl-staging.iso; the old ISO is
+only removed once that transfer succeeded, then the ISO is transferred again
+under its own name and the staging copy removed. A failed download or checksum
+check leaves the old ISO in place.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if version == "" {
 				return errors.New("version is required (e.g. --version 1.11.2)")
 			}
 
+			mode, err := proxmox.ParseImageMode(viper.GetString("proxmox.imageMode"))
+			if err != nil {
+				return err
+			}
+
 			client, err := clientFromConfig()
 			if err != nil {
 				return err
 			}
 
-			return client.GetTalosImage(cmd.Context(), version)
+			return client.GetTalosImage(cmd.Context(), version, proxmox.ImageOptions{
+				Mode:              mode,
+				Checksum:          checksum,
+				ChecksumAlgorithm: checksumAlgorithm,
+				Force:             force,
+			})
 		},
 	}
 
 	cmd.Flags().StringVar(&version, "version", "", "Talos release version (e.g. 1.11.2)")
+	cmd.Flags().String("mode", string(proxmox.ImageModeDownloadURL), "Transfer mode: download-url|relay")
+	cmd.Flags().StringVar(&checksum, "checksum", "", "Expected ISO checksum (verified by Proxmox or locally in relay mode)")
+	cmd.Flags().StringVar(&checksumAlgorithm, "checksum-algorithm", "sha256", "Checksum algorithm: md5|sha1|sha224|sha256|sha384|sha512")
+	cmd.Flags().BoolVar(&force, "force", false, "Replace the ISO even if it already exists on the storage")
+	_ = viper.BindPFlag("proxmox.imageMode", cmd.Flags().Lookup("mode"))
 	return cmd
 }
@@ -0,0 +1,55 @@
+package proxmox
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+func nodesCmd() *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "nodes",
+		Short: "List Proxmox cluster members and the nodes targeted by --nodes or --allowed-nodes",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := clientFromConfig()
+			if err != nil {
+				return err
+			}
+
+			members, err := client.ClusterNodes(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			switch output {
+			case "json":
+				return writeJSON(cmd.OutOrStdout(), members)
+			case "table", "":
+			default:
+				return fmt.Errorf("unknown output format %q (expected table or json)", output)
+			}
+
+			targets, err := client.TargetNodes(cmd.Context())
+			if err != nil {
+				return err
+			}
+			targeted := make(map[string]bool, len(targets))
+			for _, t := range targets {
+				targeted[t] = true
+			}
+
+			tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "NAME\tID\tIP\tONLINE\tTARGET")
+			for _, m := range members {
+				fmt.Fprintf(tw, "%s\t%d\t%s\t%t\t%t\n", m.Name, m.ID, m.IP, m.Online, targeted[m.Name])
+			}
+			return tw.Flush()
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format: table|json")
+	return cmd
+}
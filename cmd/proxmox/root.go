@@ -3,6 +3,7 @@ package proxmox
 import (
 	"time"
 
+	"github.com/zerodi/cctl/internal/configx"
 	"github.com/zerodi/cctl/internal/proxmox"
 
 	"github.com/spf13/cobra"
@@ -19,60 +20,71 @@ func New() *cobra.Command {
 	flags.String("url", "", "Proxmox host/IP (without scheme)")
 	flags.String("token-id", "", "Proxmox API token ID")
 	flags.String("token-secret", "", "Proxmox API token secret")
+	flags.String("username", "", "Proxmox user for ticket authentication (e.g. root@pam); takes precedence over the API token")
+	flags.String("password", "", "Proxmox password for ticket authentication")
 	flags.String("node", "", "Proxmox node name")
+	flags.StringSlice("nodes", nil, "Nodes to place ISOs/templates on (comma separated, or 'all' for every online member; default: --allowed-nodes)")
+	flags.StringSlice("allowed-nodes", nil, "Nodes the workload cluster may use (default: cluster.spec.proxmox.allowedNodes); --nodes must stay within them")
 	flags.String("iso-storage", "", "Proxmox storage target for ISO uploads (default: local)")
 	flags.String("schematic-file", "", "Path to cached Talos schematic id")
-	flags.String("schematic-yaml", "", "Talos factory schematic YAML input")
-	flags.String("template-json", "", "Template JSON payload for VM creation")
-	flags.Bool("skip-tls-verify", true, "Skip TLS verification for Proxmox API")
-	flags.Duration("http-timeout", 60*time.Second, "HTTP timeout for Proxmox/Talos requests")
+	flags.String("schematic-yaml", "", "Talos factory schematic YAML input (default: config/talos-factory-schematic.yaml, embedded copy if missing)")
+	flags.String("template-json", "", "Template JSON payload for VM creation (default: config/template.json, embedded copy if missing)")
+	flags.Bool("skip-tls-verify", false, "Skip TLS verification for Proxmox API")
+	flags.String("ca-file", "", "PEM file with the Proxmox cluster CA (e.g. /etc/pve/pve-root-ca.pem)")
+	flags.String("fingerprint", "", "Pinned SHA-256 fingerprint of the Proxmox certificate (see: proxmox trust)")
+	flags.Duration("http-timeout", 60*time.Second, "Timeout per Proxmox/Talos HTTP attempt for the response headers")
+	flags.Duration("task-timeout", 15*time.Minute, "Maximum time to wait for a Proxmox task to finish")
+	flags.Int("http-retries", 4, "Retries for transient failures of idempotent Proxmox/Talos requests (0 disables)")
+	flags.Duration("http-retry-max-elapsed", 2*time.Minute, "Maximum total time spent retrying a single request")
 
 	_ = viper.BindPFlag("proxmox.url", flags.Lookup("url"))
 	_ = viper.BindPFlag("proxmox.tokenID", flags.Lookup("token-id"))
 	_ = viper.BindPFlag("proxmox.tokenSecret", flags.Lookup("token-secret"))
+	_ = viper.BindPFlag("proxmox.username", flags.Lookup("username"))
+	_ = viper.BindPFlag("proxmox.password", flags.Lookup("password"))
 	_ = viper.BindPFlag("proxmox.node", flags.Lookup("node"))
+	_ = viper.BindPFlag("proxmox.nodes", flags.Lookup("nodes"))
+	_ = viper.BindPFlag("proxmox.allowedNodes", flags.Lookup("allowed-nodes"))
 	_ = viper.BindPFlag("proxmox.isoStorage", flags.Lookup("iso-storage"))
 	_ = viper.BindPFlag("proxmox.schematicFile", flags.Lookup("schematic-file"))
 	_ = viper.BindPFlag("proxmox.schematicYAML", flags.Lookup("schematic-yaml"))
 	_ = viper.BindPFlag("proxmox.templateJSON", flags.Lookup("template-json"))
 	_ = viper.BindPFlag("proxmox.skipTLSVerify", flags.Lookup("skip-tls-verify"))
+	_ = viper.BindPFlag("proxmox.caFile", flags.Lookup("ca-file"))
+	_ = viper.BindPFlag("proxmox.fingerprint", flags.Lookup("fingerprint"))
 	_ = viper.BindPFlag("proxmox.httpTimeout", flags.Lookup("http-timeout"))
+	_ = viper.BindPFlag("proxmox.taskTimeout", flags.Lookup("task-timeout"))
+	_ = viper.BindPFlag("proxmox.retries", flags.Lookup("http-retries"))
+	_ = viper.BindPFlag("proxmox.retryMaxElapsed", flags.Lookup("http-retry-max-elapsed"))
 
 	// Provide backwards compatibility with legacy environment variables.
 	_ = viper.BindEnv("proxmox.url", "PROXMOX_URL")
 	_ = viper.BindEnv("proxmox.tokenID", "PROXMOX_TOKEN")
 	_ = viper.BindEnv("proxmox.tokenSecret", "PROXMOX_SECRET")
+	_ = viper.BindEnv("proxmox.username", "PROXMOX_USERNAME")
+	_ = viper.BindEnv("proxmox.password", "PROXMOX_PASSWORD")
 	_ = viper.BindEnv("proxmox.node", "PVE_NODE")
 	_ = viper.BindEnv("proxmox.isoStorage", "PROXMOX_ISO_STORAGE")
 	_ = viper.BindEnv("proxmox.schematicFile", "SCHEMATIC_FILE")
 	_ = viper.BindEnv("proxmox.schematicYAML", "TALOS_SCHEMATIC_YAML")
 	_ = viper.BindEnv("proxmox.templateJSON", "TEMPLATE_JSON")
 	_ = viper.BindEnv("proxmox.skipTLSVerify", "PROXMOX_SKIP_TLS_VERIFY")
+	_ = viper.BindEnv("proxmox.fingerprint", "PROXMOX_FINGERPRINT")
 
 	cmd.AddCommand(refreshSchematicCmd())
 	cmd.AddCommand(showSchematicCmd())
 	cmd.AddCommand(clearSchematicCmd())
 	cmd.AddCommand(getTalosImageCmd())
 	cmd.AddCommand(createTemplateCmd())
+	cmd.AddCommand(imagesCmd())
+	cmd.AddCommand(vmsCmd())
+	cmd.AddCommand(nodesCmd())
+	cmd.AddCommand(trustCmd())
+	cmd.AddCommand(tokenCmd())
 
 	return cmd
 }
 
 func clientFromConfig() (*proxmox.Client, error) {
-	timeout := viper.GetDuration("proxmox.httpTimeout")
-	cfg := proxmox.Config{
-		URL:                viper.GetString("proxmox.url"),
-		TokenID:            viper.GetString("proxmox.tokenID"),
-		Secret:             viper.GetString("proxmox.tokenSecret"),
-		Node:               viper.GetString("proxmox.node"),
-		ISOStorage:         viper.GetString("proxmox.isoStorage"),
-		SchematicFile:      viper.GetString("proxmox.schematicFile"),
-		TalosSchematicPath: viper.GetString("proxmox.schematicYAML"),
-		TemplateJSONPath:   viper.GetString("proxmox.templateJSON"),
-		SkipTLSVerify:      viper.GetBool("proxmox.skipTLSVerify"),
-	}
-	if timeout > 0 {
-		cfg.Timeout = timeout
-	}
-	return proxmox.New(cfg)
+	return proxmox.New(configx.Proxmox())
 }
@@ -1,17 +1,124 @@
 package proxmox
 
-import "github.com/spf13/cobra"
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/zerodi/cctl/internal/configx"
+	"github.com/zerodi/cctl/internal/proxmox"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
 
 func createTemplateCmd() *cobra.Command {
-	return &cobra.Command{
+	var (
+		sets       []string
+		renderOnly bool
+		force      bool
+	)
+
+	cmd := &cobra.Command{
 		Use:   "create-template",
 		Short: "Create a Proxmox VM from template JSON and convert it into a template",
+		Long: `Create a Proxmox VM from template JSON and convert it into a template.
+
+The template JSON is rendered as a Go template. The values talosVersion,
+schematicID, isoStorage, diskStorage, bridge and vmid come from the cctl
+config; --set key=value overrides any of them or adds new keys.
+
+By default (--versioned) the template gets the next free VMID from --vmid-range
+and a name derived from the Talos version and schematic ID, so templates still
+in use by ProxmoxMachineTemplates are left alone. Every template is tagged with
+cctl-talos-<version>-<schematic>, and an existing template with that tag is
+reused. With --versioned=false the VMID from the template JSON is used; a VM
+there without the exact tag is only replaced with --force.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			values, err := templateValuesFromConfig(sets)
+			if err != nil {
+				return err
+			}
+
+			if renderOnly {
+				payload, err := proxmox.RenderTemplate(configx.Proxmox(), values)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(payload))
+				return nil
+			}
+
 			client, err := clientFromConfig()
 			if err != nil {
 				return err
 			}
-			return client.CreateTemplate(cmd.Context())
+
+			opts, err := templateOptionsFromConfig(force)
+			if err != nil {
+				return err
+			}
+			results, err := client.CreateTemplate(cmd.Context(), values, opts)
+			if len(results) > 0 {
+				if werr := writeTemplateResults(cmd.OutOrStdout(), results); werr != nil {
+					return werr
+				}
+			}
+			return err
 		},
 	}
+
+	flags := cmd.Flags()
+	flags.String("talos-version", "", "Talos version of the ISO attached to the template (e.g. 1.11.2)")
+	flags.String("disk-storage", "local-zfs", "Proxmox storage for the template boot disk")
+	flags.String("bridge", "vmbr0", "Network bridge for the template NIC")
+	flags.Int("vmid", 900, "VMID of the template")
+	flags.StringArrayVar(&sets, "set", nil, "Override a template value (key=value, repeatable)")
+	flags.BoolVar(&renderOnly, "render-only", false, "Print the rendered payload without touching Proxmox")
+	flags.Bool("versioned", true, "Allocate a free VMID and name the template after the Talos version and schematic")
+	flags.String("vmid-range", "900-999", "VMID range for versioned templates (min-max)")
+	flags.BoolVar(&force, "force", false, "Replace an existing VM at the target VMID that is not a template with the matching tag")
+
+	_ = viper.BindPFlag("proxmox.talosVersion", flags.Lookup("talos-version"))
+	_ = viper.BindPFlag("proxmox.diskStorage", flags.Lookup("disk-storage"))
+	_ = viper.BindPFlag("proxmox.bridge", flags.Lookup("bridge"))
+	_ = viper.BindPFlag("proxmox.templateVMID", flags.Lookup("vmid"))
+	_ = viper.BindPFlag("proxmox.templateVersioned", flags.Lookup("versioned"))
+	_ = viper.BindPFlag("proxmox.templateVMIDRange", flags.Lookup("vmid-range"))
+	return cmd
+}
+
+func writeTemplateResults(w io.Writer, results []proxmox.TemplateResult) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NODE\tVMID\tNAME\tRESULT")
+	for _, r := range results {
+		switch {
+		case r.Error != "":
+			fmt.Fprintf(tw, "%s\t-\t-\tfailed: %s\n", r.Node, r.Error)
+		case r.Template.Reused:
+			fmt.Fprintf(tw, "%s\t%d\t%s\treused\n", r.Node, r.Template.VMID, r.Template.Name)
+		default:
+			fmt.Fprintf(tw, "%s\t%d\t%s\tcreated\n", r.Node, r.Template.VMID, r.Template.Name)
+		}
+	}
+	return tw.Flush()
+}
+
+func templateValuesFromConfig(sets []string) (proxmox.TemplateValues, error) {
+	values := configx.TemplateValues()
+	for _, kv := range sets {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --set %q (expected key=value)", kv)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+func templateOptionsFromConfig(force bool) (proxmox.TemplateOptions, error) {
+	opts, err := configx.TemplateOptions()
+	opts.Force = force
+	return opts, err
 }
@@ -0,0 +1,82 @@
+package proxmox
+
+import (
+	"fmt"
+
+	"github.com/zerodi/cctl/internal/configx"
+	"github.com/zerodi/cctl/internal/proxmox"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func tokenCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "token",
+		Short: "Manage Proxmox API tokens",
+	}
+	cmd.AddCommand(tokenCreateCmd())
+	return cmd
+}
+
+func tokenCreateCmd() *cobra.Command {
+	var (
+		opts       proxmox.TokenOptions
+		noPersist  bool
+		showSecret bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a least-privilege user, role and API token for CAPMOX",
+		Long: `Create a least-privilege user, role and API token for the Cluster API
+Proxmox provider (CAPMOX) and store the token ID and secret in the cctl config.
+
+Run it with bootstrap credentials, e.g.:
+  cctl --config cctl.yaml proxmox --username root@pam --password ... token create`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := clientFromConfig()
+			if err != nil {
+				return err
+			}
+
+			token, err := client.CreateAPIToken(cmd.Context(), opts)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), token.ID)
+			if noPersist || showSecret {
+				fmt.Fprintln(cmd.OutOrStdout(), token.Secret)
+			}
+			if noPersist {
+				return nil
+			}
+
+			path, err := configx.Persist(map[string]any{
+				"proxmox.tokenID":     token.ID,
+				"proxmox.tokenSecret": token.Secret,
+			})
+			if err != nil {
+				// The secret cannot be retrieved again; make sure it is not lost.
+				if !showSecret {
+					fmt.Fprintln(cmd.OutOrStdout(), token.Secret)
+				}
+				return fmt.Errorf("store token in config: %w", err)
+			}
+			log.Info().Str("config", path).Str("token", token.ID).Msg("Stored Proxmox API token")
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&opts.UserID, "user", "capmox@pve", "Proxmox user to create for CAPMOX")
+	flags.StringVar(&opts.RoleID, "role", "CAPMOX", "Proxmox role to create or update")
+	flags.StringVar(&opts.TokenName, "token-name", "capi", "API token name")
+	flags.StringSliceVar(&opts.Privileges, "privs", proxmox.CAPMOXPrivileges, "Privileges granted by the role")
+	flags.StringVar(&opts.Path, "acl-path", "/", "ACL path the role is granted on")
+	flags.BoolVar(&opts.Rotate, "rotate", false, "Replace an existing token with the same name")
+	flags.BoolVar(&noPersist, "no-persist", false, "Print the token instead of storing it in the config")
+	flags.BoolVar(&showSecret, "show-secret", false, "Also print the token secret")
+	return cmd
+}
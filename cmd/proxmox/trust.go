@@ -0,0 +1,64 @@
+package proxmox
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zerodi/cctl/internal/configx"
+	"github.com/zerodi/cctl/internal/executil"
+	"github.com/zerodi/cctl/internal/proxmox"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func trustCmd() *cobra.Command {
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "trust",
+		Short: "Fetch the Proxmox certificate, show its fingerprint and pin it in the config",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cert, err := proxmox.FetchCertificate(cmd.Context(), viper.GetString("proxmox.url"))
+			if err != nil {
+				return err
+			}
+			fingerprint := proxmox.Fingerprint(cert)
+
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "Subject:     %s\n", cert.Subject)
+			fmt.Fprintf(out, "Issuer:      %s\n", cert.Issuer)
+			fmt.Fprintf(out, "Valid:       %s - %s\n", cert.NotBefore.Format("2006-01-02"), cert.NotAfter.Format("2006-01-02"))
+			names := append([]string{}, cert.DNSNames...)
+			for _, ip := range cert.IPAddresses {
+				names = append(names, ip.String())
+			}
+			if len(names) > 0 {
+				fmt.Fprintf(out, "Names:       %s\n", strings.Join(names, ", "))
+			}
+			fmt.Fprintf(out, "Fingerprint: %s\n", fingerprint)
+
+			if !yes {
+				ok, err := executil.Confirm(cmd.InOrStdin(), cmd.ErrOrStderr(), "Pin this fingerprint in the cctl config?")
+				if err != nil {
+					return err
+				}
+				if !ok {
+					log.Info().Msg("Fingerprint not stored")
+					return nil
+				}
+			}
+
+			path, err := configx.Persist(map[string]any{"proxmox.fingerprint": fingerprint})
+			if err != nil {
+				return err
+			}
+			log.Info().Str("config", path).Str("fingerprint", fingerprint).Msg("Pinned Proxmox certificate fingerprint")
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Store the fingerprint without asking")
+	return cmd
+}
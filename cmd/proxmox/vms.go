@@ -0,0 +1,194 @@
+package proxmox
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/zerodi/cctl/internal/capix"
+	"github.com/zerodi/cctl/internal/configx"
+	"github.com/zerodi/cctl/internal/executil"
+	"github.com/zerodi/cctl/internal/proxmox"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+type vmRow struct {
+	proxmox.VM
+	IPs []string `json:"ips,omitempty"`
+}
+
+func vmsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "vms",
+		Short: "Inspect Proxmox VMs and correlate them with Cluster API machines",
+	}
+	cmd.AddCommand(vmsListCmd())
+	return cmd
+}
+
+func vmsListCmd() *cobra.Command {
+	var (
+		output        string
+		orphans       bool
+		deleteOrphans bool
+		yes           bool
+		kubeconfig    string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List QEMU VMs across all Proxmox nodes",
+		Long: `List QEMU VMs across all Proxmox nodes with status, tags, guest agent IPs
+and template flag.
+
+With --orphans only VMs named like a machine of the cluster (--cluster-name)
+that no ProxmoxMachine in the management cluster owns are listed: CAPMOX names
+VMs <cluster>-control-plane-<suffix> and <cluster>-<pool>-<hash>-<suffix> for
+the worker pools of cluster.spec.workers, so VMs of other clusters whose name
+merely starts with <cluster>- are never listed. --delete-orphans removes them
+after confirmation; it refuses to run when the management cluster has no
+ProxmoxMachines at all, which usually means the wrong --kubeconfig.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if deleteOrphans {
+				orphans = true
+			}
+			ctx := cmd.Context()
+
+			client, err := clientFromConfig()
+			if err != nil {
+				return err
+			}
+
+			vms, err := client.ListVMs(ctx)
+			if err != nil {
+				return err
+			}
+
+			if orphans {
+				machines, err := capix.ListProxmoxMachines(ctx, kubeconfig, "")
+				if err != nil {
+					return err
+				}
+				if deleteOrphans && len(machines) == 0 {
+					return errors.New("no ProxmoxMachines found in the management cluster; refusing to delete orphans (check --kubeconfig)")
+				}
+				pools, err := configx.WorkerPools()
+				if err != nil {
+					return err
+				}
+				names := capix.MachineNamePattern(configx.Cluster().Name, pools)
+				vms = orphanVMs(vms, machineOwners(machines), names)
+			}
+
+			rows := make([]vmRow, 0, len(vms))
+			for _, vm := range vms {
+				row := vmRow{VM: vm}
+				if vm.Status == "running" {
+					ips, err := client.VMIPs(ctx, vm)
+					if err != nil {
+						log.Debug().Err(err).Int64("vmid", vm.VMID).Msg("Guest agent unavailable")
+					}
+					row.IPs = ips
+				}
+				rows = append(rows, row)
+			}
+
+			switch output {
+			case "json":
+				if err := writeJSON(cmd.OutOrStdout(), rows); err != nil {
+					return err
+				}
+			case "table", "":
+				if err := writeVMsTable(cmd.OutOrStdout(), rows); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("unknown output format %q (expected table or json)", output)
+			}
+
+			if !deleteOrphans || len(rows) == 0 {
+				return nil
+			}
+			if !yes {
+				ok, err := executil.Confirm(cmd.InOrStdin(), cmd.ErrOrStderr(), fmt.Sprintf("Delete %d orphaned VM(s)?", len(rows)))
+				if err != nil {
+					return err
+				}
+				if !ok {
+					log.Info().Msg("Aborted; no VMs deleted")
+					return nil
+				}
+			}
+
+			var errs []error
+			for _, row := range rows {
+				if err := client.DeleteVM(ctx, row.VM); err != nil {
+					errs = append(errs, fmt.Errorf("delete vm %d: %w", row.VMID, err))
+				}
+			}
+			return errors.Join(errs...)
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format: table|json")
+	cmd.Flags().BoolVar(&orphans, "orphans", false, "Only list VMs not owned by any ProxmoxMachine")
+	cmd.Flags().BoolVar(&deleteOrphans, "delete-orphans", false, "Delete orphaned VMs after confirmation (implies --orphans)")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Do not ask for confirmation")
+	cmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Management cluster kubeconfig (default: current kubectl context)")
+	return cmd
+}
+
+// orphanVMs returns the VMs that are named like a machine of the cluster (see capix.MachineNamePattern)
+// but owned by no ProxmoxMachine. Templates are never orphans.
+func orphanVMs(vms []proxmox.VM, owners map[string]string, names *regexp.Regexp) []proxmox.VM {
+	var out []proxmox.VM
+	for _, vm := range vms {
+		if vm.IsTemplate() || !names.MatchString(vm.Name) || ownerOf(owners, vm) != "" {
+			continue
+		}
+		out = append(out, vm)
+	}
+	return out
+}
+
+// machineOwners indexes ProxmoxMachines by VMID and by name; VMs are named after their machine
+// before the provider records the VMID.
+func machineOwners(machines []capix.ProxmoxMachine) map[string]string {
+	owners := make(map[string]string, 2*len(machines))
+	for _, m := range machines {
+		ref := m.Namespace + "/" + m.Name
+		owners["name:"+m.Name] = ref
+		if m.VMID != 0 {
+			owners[fmt.Sprintf("vmid:%d", m.VMID)] = ref
+		}
+	}
+	return owners
+}
+
+func ownerOf(owners map[string]string, vm proxmox.VM) string {
+	if owner, ok := owners[fmt.Sprintf("vmid:%d", vm.VMID)]; ok {
+		return owner
+	}
+	return owners["name:"+vm.Name]
+}
+
+func writeVMsTable(w io.Writer, rows []vmRow) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VMID\tNAME\tNODE\tSTATUS\tTEMPLATE\tTAGS\tIPS")
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%t\t%s\t%s\n",
+			row.VMID,
+			row.Name,
+			row.Node,
+			row.Status,
+			row.IsTemplate(),
+			strings.Join(row.TagList(), ","),
+			strings.Join(row.IPs, ","))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,64 @@
+package proxmox
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/zerodi/cctl/internal/capix"
+	"github.com/zerodi/cctl/internal/proxmox"
+)
+
+func TestOwnerOf(t *testing.T) {
+	owners := machineOwners([]capix.ProxmoxMachine{
+		{Namespace: "default", Name: "prod-control-plane-abcde", VMID: 101},
+		{Namespace: "default", Name: "prod-workers-7d9f8-x2k4p"}, // VM not created yet
+	})
+	tests := []struct {
+		name string
+		vm   proxmox.VM
+		want string
+	}{
+		{"by vmid", proxmox.VM{VMID: 101, Name: "renamed"}, "default/prod-control-plane-abcde"},
+		{"by name", proxmox.VM{VMID: 102, Name: "prod-workers-7d9f8-x2k4p"}, "default/prod-workers-7d9f8-x2k4p"},
+		{"unowned", proxmox.VM{VMID: 103, Name: "prod-workers-7d9f8-zzzzz"}, ""},
+		{"vmid zero not indexed", proxmox.VM{VMID: 0, Name: "other"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ownerOf(owners, tt.vm); got != tt.want {
+				t.Fatalf("ownerOf() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrphanVMs(t *testing.T) {
+	owners := machineOwners([]capix.ProxmoxMachine{
+		{Namespace: "default", Name: "prod-control-plane-abcde", VMID: 101},
+	})
+	names := capix.MachineNamePattern("prod", []string{"workers", "gpu"})
+	tests := []struct {
+		name   string
+		vm     proxmox.VM
+		orphan bool
+	}{
+		{"owned control plane", proxmox.VM{VMID: 101, Name: "prod-control-plane-abcde"}, false},
+		{"unowned control plane", proxmox.VM{VMID: 104, Name: "prod-control-plane-fghij"}, true},
+		{"unowned worker", proxmox.VM{VMID: 105, Name: "prod-workers-7d9f8-x2k4p"}, true},
+		{"unowned second pool", proxmox.VM{VMID: 106, Name: "prod-gpu-5c6b7d8f9-q1w2e"}, true},
+		{"template", proxmox.VM{VMID: 900, Name: "prod-workers-7d9f8-x2k4p", Template: 1}, false},
+		{"other cluster control plane", proxmox.VM{VMID: 201, Name: "prod-eu-control-plane-abcde"}, false},
+		{"other cluster worker", proxmox.VM{VMID: 202, Name: "prod-eu-workers-7d9f8-x2k4p"}, false},
+		{"unknown pool", proxmox.VM{VMID: 203, Name: "prod-db-7d9f8-x2k4p"}, false},
+		{"prefix only", proxmox.VM{VMID: 204, Name: "prod-jumphost"}, false},
+		{"bare pool name", proxmox.VM{VMID: 205, Name: "prod-workers"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := orphanVMs([]proxmox.VM{tt.vm}, owners, names)
+			if orphan := slices.ContainsFunc(got, func(vm proxmox.VM) bool { return vm.VMID == tt.vm.VMID }); orphan != tt.orphan {
+				t.Fatalf("orphan = %t, want %t", orphan, tt.orphan)
+			}
+		})
+	}
+}
@@ -12,7 +12,10 @@ import (
 	"github.com/zerodi/cctl/cmd/kind"
 	"github.com/zerodi/cctl/cmd/proxmox"
 	"github.com/zerodi/cctl/cmd/secrets"
+	"github.com/zerodi/cctl/cmd/talos"
+	"github.com/zerodi/cctl/cmd/templates"
 	"github.com/zerodi/cctl/internal/configx"
+	"github.com/zerodi/cctl/internal/exitx"
 	logx "github.com/zerodi/cctl/internal/logx"
 
 	"github.com/spf13/cobra"
@@ -26,6 +29,11 @@ var (
 
 func Execute() error { return rootCmd().Execute() }
 
+// ExitCode maps an error returned by Execute to the process exit code.
+func ExitCode(err error) int {
+	return exitx.Code(err)
+}
+
 func rootCmd() *cobra.Command {
 	bindLegacyEnv()
 	clusterDefaults := configx.Cluster()
@@ -85,6 +93,8 @@ func rootCmd() *cobra.Command {
 	root.AddCommand(proxmox.New())
 	root.AddCommand(cilium.New())
 	root.AddCommand(secrets.New())
+	root.AddCommand(talos.New())
+	root.AddCommand(templates.New())
 
 	// Version
 	root.AddCommand(&cobra.Command{
@@ -0,0 +1,13 @@
+package talos
+
+import "github.com/spf13/cobra"
+
+// New returns the `talos` command group.
+func New() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "talos",
+		Short: "Talos OS lifecycle of the workload cluster nodes",
+	}
+	cmd.AddCommand(upgradeCmd())
+	return cmd
+}
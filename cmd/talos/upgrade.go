@@ -0,0 +1,120 @@
+package talos
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zerodi/cctl/internal/capix"
+	"github.com/zerodi/cctl/internal/configx"
+	"github.com/zerodi/cctl/internal/proxmox"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func upgradeCmd() *cobra.Command {
+	var (
+		version    string
+		kubeconfig string
+		timeout    time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Roll the workload cluster nodes to a new Talos version",
+		Long: `Roll the workload cluster nodes to a new Talos version.
+
+The Proxmox steps run in order: the Talos factory schematic is refreshed, the
+ISO of the new version is imported (see: proxmox get-talos-image) and a
+versioned template is created from it on every target node (see: proxmox
+create-template --versioned). The proxmox.* settings of the cctl config apply.
+
+ProxmoxMachineTemplates are immutable, so every template used by the cluster
+is copied to <name>-talos-<version>-<schematic> with the new templateID. The
+TalosControlPlane is repointed first and its rollout awaited, then every
+MachineDeployment in turn. The rollover stops as soon as a MachineHealthCheck
+remediates a machine; rerunning the command reuses the templates created so
+far and continues.
+
+The references are patched with the field manager cctl-upgrade, so a later
+capi deploy of the old manifests reports a conflict. Update the manifests to
+the new template names; capi deploy --prune then removes the old templates.
+When a config file is in use, proxmox.talosVersion is updated.`,
+		Example: `  cctl talos upgrade --version 1.12.0`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			version = strings.TrimPrefix(version, "v")
+			if version == "" {
+				return errors.New("version is required (e.g. --version 1.12.0)")
+			}
+
+			mode, err := proxmox.ParseImageMode(viper.GetString("proxmox.imageMode"))
+			if err != nil {
+				return err
+			}
+			values := configx.TemplateValues()
+			values["talosVersion"] = version
+			opts, err := configx.TemplateOptions()
+			if err != nil {
+				return err
+			}
+			opts.Versioned = true
+
+			client, err := proxmox.New(configx.Proxmox())
+			if err != nil {
+				return err
+			}
+			settings := configx.Cluster()
+			log.Info().Str("cluster", settings.Name).Str("version", version).Msg("talos upgrade")
+
+			schematicID, err := client.RefreshSchematic(cmd.Context())
+			if err != nil {
+				return err
+			}
+			if err := client.GetTalosImage(cmd.Context(), version, proxmox.ImageOptions{Mode: mode}); err != nil {
+				return err
+			}
+			results, err := client.CreateTemplate(cmd.Context(), values, opts)
+			if err != nil {
+				return fmt.Errorf("create template: %w", err)
+			}
+			templates := make(map[string]int64, len(results))
+			for _, r := range results {
+				log.Info().Str("node", r.Node).Int64("vmid", r.Template.VMID).Str("name", r.Template.Name).
+					Bool("reused", r.Template.Reused).Msg("Talos template ready")
+				templates[r.Node] = r.Template.VMID
+			}
+
+			err = capix.RolloverTemplates(cmd.Context(), capix.WaitOptions{
+				Kubeconfig: kubeconfig,
+				Namespace:  settings.Namespace,
+				Cluster:    settings.Name,
+				Timeout:    timeout,
+			}, capix.TemplateRollover{
+				Suffix:    proxmox.VersionedTemplateName(version, schematicID),
+				Templates: templates,
+			})
+			if err != nil {
+				return err
+			}
+
+			if viper.ConfigFileUsed() == "" {
+				return nil
+			}
+			path, err := configx.Persist(map[string]any{"proxmox.talosVersion": version})
+			if err != nil {
+				return err
+			}
+			log.Info().Str("config", path).Str("version", version).Msg("Updated proxmox.talosVersion")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&version, "version", "", "Target Talos version (e.g. 1.12.0)")
+	cmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Management cluster kubeconfig (default: current kubectl context)")
+	cmd.Flags().DurationVar(&timeout, "timeout", 30*time.Minute, "Maximum time to wait for each rollout")
+	return cmd
+}
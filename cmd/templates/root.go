@@ -0,0 +1,86 @@
+package templates
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/zerodi/cctl/config"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// New returns the templates command group.
+func New() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "templates",
+		Short: "Inspect and export the reference manifests and defaults embedded in cctl",
+		Long: `Inspect and export the reference manifests and defaults embedded in cctl.
+
+Commands fall back to these copies when their default file (under ./config)
+is missing, so cctl works outside of a repository checkout.`,
+	}
+	cmd.AddCommand(listCmd())
+	cmd.AddCommand(showCmd())
+	cmd.AddCommand(exportCmd())
+	return cmd
+}
+
+func listCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List embedded templates",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			items, err := config.List()
+			if err != nil {
+				return err
+			}
+			tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "NAME\tSIZE\tDESCRIPTION")
+			for _, t := range items {
+				fmt.Fprintf(tw, "%s\t%d\t%s\n", t.Name, t.Size, t.Description)
+			}
+			return tw.Flush()
+		},
+	}
+}
+
+func showCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show NAME",
+		Short: "Print an embedded template",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			raw, err := config.Read(args[0])
+			if err != nil {
+				return err
+			}
+			_, err = cmd.OutOrStdout().Write(raw)
+			return err
+		},
+	}
+}
+
+func exportCmd() *cobra.Command {
+	var (
+		dir   string
+		force bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "export [NAME...]",
+		Short: "Copy embedded templates to disk (all when no name is given)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			written, err := config.Export(dir, args, force)
+			for _, path := range written {
+				log.Info().Str("path", path).Msg("Template exported")
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&dir, "dir", config.Dir, "Target directory")
+	cmd.Flags().BoolVar(&force, "force", false, "Overwrite existing files")
+	return cmd
+}
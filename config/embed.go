@@ -0,0 +1,131 @@
+// Package config embeds the reference manifests and defaults shipped with cctl, so commands
+// keep working when they are run outside of a repository checkout.
+package config
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+//go:embed capi/*.yaml kind.yaml talos-factory-schematic.yaml template.json
+var files embed.FS
+
+// Dir is the directory the embedded files live in within a checkout; default paths point into it.
+const Dir = "config"
+
+var descriptions = map[string]string{
+	"capi/health-check.yaml":             "MachineHealthChecks for control plane and workers",
+	"capi/proxmox-cluster.yaml":          "Cluster and ProxmoxCluster",
+	"capi/proxmox-machine-template.yaml": "ProxmoxMachineTemplates for control plane and workers",
+	"capi/talos-control-plane.yaml":      "TalosControlPlane",
+	"capi/talos-worker.yaml":             "TalosConfigTemplate and worker MachineDeployment",
+	"kind.yaml":                          "kind config for the bootstrap cluster",
+	"talos-factory-schematic.yaml":       "Talos Image Factory schematic",
+	"template.json":                      "Proxmox VM template descriptor (Go template)",
+}
+
+// Template describes an embedded file.
+type Template struct {
+	Name        string `json:"name"`
+	Size        int64  `json:"size"`
+	Description string `json:"description"`
+}
+
+// List returns the embedded files sorted by name.
+func List() ([]Template, error) {
+	var out []Template
+	err := fs.WalkDir(files, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		out = append(out, Template{Name: p, Size: info.Size(), Description: descriptions[p]})
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, err
+}
+
+// Read returns the content of an embedded file.
+func Read(name string) ([]byte, error) {
+	raw, err := files.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("no embedded template %q (see: cctl templates list)", name)
+	}
+	return raw, nil
+}
+
+// Export writes the named embedded files (all when names is empty) below dir, keeping their
+// relative paths. Existing files are only overwritten with force.
+func Export(dir string, names []string, force bool) ([]string, error) {
+	if len(names) == 0 {
+		all, err := List()
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range all {
+			names = append(names, t.Name)
+		}
+	}
+
+	written := make([]string, 0, len(names))
+	for _, name := range names {
+		raw, err := Read(name)
+		if err != nil {
+			return written, err
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if _, err := os.Stat(target); err == nil && !force {
+			return written, fmt.Errorf("%s already exists (use --force to overwrite)", target)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return written, fmt.Errorf("ensure output dir: %w", err)
+		}
+		if err := os.WriteFile(target, raw, 0o644); err != nil {
+			return written, fmt.Errorf("write %s: %w", target, err)
+		}
+		written = append(written, target)
+	}
+	return written, nil
+}
+
+// ReadFile reads p from disk. When p is missing and points at a default location
+// (config/<name> or <name>), the embedded copy is returned instead.
+func ReadFile(p string) ([]byte, error) {
+	raw, err := os.ReadFile(p)
+	if err == nil || !errors.Is(err, os.ErrNotExist) {
+		return raw, err
+	}
+	name, ok := embeddedName(p)
+	if !ok {
+		return nil, err
+	}
+	log.Debug().Str("path", p).Str("template", name).Msg("File not found; using embedded default")
+	return files.ReadFile(name)
+}
+
+func embeddedName(p string) (string, bool) {
+	name := filepath.ToSlash(filepath.Clean(p))
+	name = strings.TrimPrefix(name, Dir+"/")
+	if name == Dir {
+		name = "."
+	}
+	if name == "." || !fs.ValidPath(name) {
+		return "", false
+	}
+	if _, err := fs.Stat(files, name); err != nil {
+		return "", false
+	}
+	return name, true
+}
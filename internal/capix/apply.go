@@ -0,0 +1,343 @@
+package capix
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// FieldManager is the server-side apply field manager used for objects applied by cctl.
+const FieldManager = "cctl"
+
+// Apply actions reported in ApplyResult.
+const (
+	ActionCreated    = "created"
+	ActionConfigured = "configured"
+	ActionUnchanged  = "unchanged"
+)
+
+// Dry-run modes for ApplyOptions.DryRun.
+const (
+	DryRunNone   = "none"
+	DryRunClient = "client" // Compare the manifests with the live objects without sending them
+	DryRunServer = "server" // Send the apply request with dryRun=All; nothing is persisted
+)
+
+// ApplyOptions configures Apply.
+type ApplyOptions struct {
+	Kubeconfig     string // Management cluster kubeconfig (empty for the default loading rules)
+	Namespace      string // Namespace for namespaced objects that do not set one
+	ForceConflicts bool   // Take over fields owned by other field managers
+	DryRun         string // One of DryRunNone (default), DryRunClient or DryRunServer
+
+	// Inventory records the applied objects in the ConfigMap InventoryName(Inventory) in Namespace.
+	// With Prune, recorded objects that are no longer in the manifests are deleted.
+	Inventory    string
+	Prune        bool
+	PruneTimeout time.Duration // Maximum time to wait for each group of pruned objects (default 10m)
+}
+
+// ApplyResult is the outcome of applying a single object.
+type ApplyResult struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	Action     string `json:"action"`
+	DryRun     string `json:"dryRun,omitempty"`
+}
+
+// String renders the result like kubectl (kind/name action).
+func (r ApplyResult) String() string {
+	s := fmt.Sprintf("%s/%s %s", strings.ToLower(r.Kind), r.Name, r.Action)
+	switch r.DryRun {
+	case DryRunClient:
+		s += " (dry run)"
+	case DryRunServer:
+		s += " (server dry run)"
+	}
+	return s
+}
+
+// Apply server-side applies the manifests in path (a file or a directory of .yaml/.yml/.json files)
+// and reports for every object whether it was created, configured or left unchanged. With
+// opts.Prune, objects recorded in the inventory but missing from the manifests are reported as pruned.
+func Apply(ctx context.Context, path string, opts ApplyOptions) ([]ApplyResult, error) {
+	switch opts.DryRun {
+	case "", DryRunNone:
+		opts.DryRun = ""
+	case DryRunClient, DryRunServer:
+	default:
+		return nil, fmt.Errorf("invalid dry-run mode %q (expected none, client or server)", opts.DryRun)
+	}
+	if opts.Prune && opts.Inventory == "" {
+		return nil, errors.New("prune requires an inventory")
+	}
+
+	objs, err := LoadManifests(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := newKubeClient(opts.Kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	results, err := applyObjects(ctx, c, objs, opts)
+	if err != nil || opts.Inventory == "" {
+		return results, err
+	}
+	return syncInventory(ctx, c, objs, results, opts)
+}
+
+// syncInventory prunes stale objects if requested and records the applied objects. Without Prune,
+// stale objects stay in the inventory so that a later deploy with --prune still removes them.
+func syncInventory(ctx context.Context, c ctrlclient.Client, objs []*unstructured.Unstructured, results []ApplyResult, opts ApplyOptions) ([]ApplyResult, error) {
+	inv := newInventory(opts.Inventory, opts.Namespace)
+	recorded, err := inv.read(ctx, c)
+	if err != nil {
+		return results, err
+	}
+	applied := make([]ObjectRef, 0, len(objs))
+	for _, obj := range objs {
+		applied = append(applied, refOf(obj))
+	}
+
+	keep := recorded
+	if opts.Prune {
+		var pruned []ApplyResult
+		pruned, keep, err = prune(ctx, c, staleRefs(recorded, objs), opts)
+		results = append(results, pruned...)
+	}
+	if opts.DryRun != "" {
+		return results, err
+	}
+	if werr := inv.write(ctx, c, mergeRefs(keep, applied)); werr != nil && err == nil {
+		err = werr
+	}
+	return results, err
+}
+
+func applyObjects(ctx context.Context, c ctrlclient.Client, objs []*unstructured.Unstructured, opts ApplyOptions) ([]ApplyResult, error) {
+	results := make([]ApplyResult, 0, len(objs))
+	for _, obj := range objs {
+		if err := setNamespace(c, obj, opts.Namespace); err != nil {
+			return results, err
+		}
+		result, err := applyObject(ctx, c, obj, opts)
+		if err != nil {
+			return results, err
+		}
+		log.Debug().Str("object", result.String()).Str("namespace", result.Namespace).Msg("Applied")
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func applyObject(ctx context.Context, c ctrlclient.Client, obj *unstructured.Unstructured, opts ApplyOptions) (ApplyResult, error) {
+	result := ApplyResult{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+		DryRun:     opts.DryRun,
+	}
+
+	live, err := getLive(ctx, c, obj)
+	if err != nil {
+		return result, err
+	}
+
+	if opts.DryRun == DryRunClient {
+		switch {
+		case live == nil:
+			result.Action = ActionCreated
+		case isSubset(obj.Object, live.Object):
+			result.Action = ActionUnchanged
+		default:
+			result.Action = ActionConfigured
+		}
+		return result, nil
+	}
+
+	applied, err := serverApply(ctx, c, obj, opts)
+	if err != nil {
+		return result, err
+	}
+
+	switch {
+	case live == nil:
+		result.Action = ActionCreated
+	case opts.DryRun == DryRunServer && reflect.DeepEqual(normalize(live), normalize(applied)):
+		result.Action = ActionUnchanged
+	case opts.DryRun == "" && applied.GetResourceVersion() == live.GetResourceVersion():
+		result.Action = ActionUnchanged
+	default:
+		result.Action = ActionConfigured
+	}
+	return result, nil
+}
+
+// getLive returns the live object, or nil if it does not exist.
+func getLive(ctx context.Context, c ctrlclient.Client, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	live := newObject(obj.GroupVersionKind())
+	if err := c.Get(ctx, ctrlclient.ObjectKeyFromObject(obj), live); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get %s: %w", describeObject(obj), err)
+	}
+	return live, nil
+}
+
+// serverApply server-side applies obj (with dryRun=All for DryRunServer) and returns the resulting object.
+func serverApply(ctx context.Context, c ctrlclient.Client, obj *unstructured.Unstructured, opts ApplyOptions) (*unstructured.Unstructured, error) {
+	patchOpts := []ctrlclient.PatchOption{ctrlclient.FieldOwner(FieldManager)}
+	if opts.ForceConflicts {
+		patchOpts = append(patchOpts, ctrlclient.ForceOwnership)
+	}
+	if opts.DryRun == DryRunServer {
+		patchOpts = append(patchOpts, ctrlclient.DryRunAll)
+	}
+	applied := obj.DeepCopy()
+	if err := c.Patch(ctx, applied, ctrlclient.Apply, patchOpts...); err != nil {
+		return nil, fmt.Errorf("apply %s: %w", describeObject(obj), err)
+	}
+	return applied, nil
+}
+
+// setNamespace defaults the namespace of namespaced objects and clears it on cluster-scoped ones.
+// The scope is resolved through API discovery.
+func setNamespace(c ctrlclient.Client, obj *unstructured.Unstructured, namespace string) error {
+	namespaced, err := c.IsObjectNamespaced(obj)
+	if err != nil {
+		return fmt.Errorf("resolve %s: %w", describeObject(obj), err)
+	}
+	switch {
+	case !namespaced:
+		obj.SetNamespace("")
+	case obj.GetNamespace() == "" && namespace != "":
+		obj.SetNamespace(namespace)
+	case obj.GetNamespace() == "":
+		obj.SetNamespace("default")
+	}
+	return nil
+}
+
+func describeObject(obj *unstructured.Unstructured) string {
+	ref := obj.GetKind() + "/" + obj.GetName()
+	if ns := obj.GetNamespace(); ns != "" {
+		ref = ns + "/" + ref
+	}
+	return ref
+}
+
+// LoadManifests decodes all objects from a multi-document YAML (or JSON) file, or from the
+// .yaml/.yml/.json files of a directory in lexical order. Namespaces and CRDs come first.
+func LoadManifests(path string) ([]*unstructured.Unstructured, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		files = files[:0]
+		for _, e := range entries {
+			switch strings.ToLower(filepath.Ext(e.Name())) {
+			case ".yaml", ".yml", ".json":
+				if !e.IsDir() {
+					files = append(files, filepath.Join(path, e.Name()))
+				}
+			}
+		}
+	}
+
+	var objs []*unstructured.Unstructured
+	for _, file := range files {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		decoded, err := decodeManifests(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		objs = append(objs, decoded...)
+	}
+	if len(objs) == 0 {
+		return nil, fmt.Errorf("no manifests found in %s", path)
+	}
+
+	sort.SliceStable(objs, func(i, j int) bool { return applyRank(objs[i]) < applyRank(objs[j]) })
+	return objs, nil
+}
+
+func decodeManifests(raw []byte) ([]*unstructured.Unstructured, error) {
+	dec := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(raw), 4096)
+	var objs []*unstructured.Unstructured
+	for {
+		var doc json.RawMessage
+		if err := dec.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objs, nil
+			}
+			return nil, err
+		}
+		if trimmed := bytes.TrimSpace(doc); len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) || bytes.Equal(trimmed, []byte("{}")) {
+			continue
+		}
+
+		// utiljson keeps integers as int64, like in objects read from the API server.
+		obj := &unstructured.Unstructured{}
+		if err := utiljson.Unmarshal(doc, &obj.Object); err != nil {
+			return nil, err
+		}
+		if obj.IsList() {
+			list, err := obj.ToList()
+			if err != nil {
+				return nil, err
+			}
+			for i := range list.Items {
+				objs = append(objs, &list.Items[i])
+			}
+			continue
+		}
+		if obj.GetAPIVersion() == "" || obj.GetKind() == "" {
+			return nil, fmt.Errorf("object %q is missing apiVersion or kind", obj.GetName())
+		}
+		if obj.GetName() == "" {
+			return nil, fmt.Errorf("%s object is missing metadata.name", obj.GetKind())
+		}
+		objs = append(objs, obj)
+	}
+}
+
+func applyRank(obj *unstructured.Unstructured) int {
+	switch obj.GetKind() {
+	case "Namespace":
+		return 0
+	case "CustomResourceDefinition":
+		return 1
+	}
+	return 2
+}
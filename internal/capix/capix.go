@@ -2,48 +2,70 @@ package capix
 
 import (
 	"context"
-	"os"
-	"os/exec"
+	"sort"
+	"time"
 
 	"github.com/rs/zerolog/log"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client"
 )
 
-func Init(clusterConfig, core string, boots, cps, infras []string, kubeconfig string) error {
-	c, err := client.New(context.Background(), clusterConfig)
+// InitOptions selects the providers installed by Init.
+type InitOptions struct {
+	ClusterctlConfig string            // Path to clusterctl.yaml (empty for the clusterctl default)
+	Kubeconfig       string            // Target cluster kubeconfig (empty for the default loading rules)
+	Core             string            // Core provider, e.g. "cluster-api" or "cluster-api:v1.11.2"
+	Bootstrap        []string          // Bootstrap providers
+	ControlPlane     []string          // Control plane providers
+	Infrastructure   []string          // Infrastructure providers
+	IPAM             []string          // IPAM providers
+	Addon            []string          // Addon providers
+	Versions         map[string]string // Pinned versions for providers given without one (see configx.ProviderVersions)
+	Variables        map[string]string // Override clusterctl's variable resolution, e.g. the CAPMOX credentials
+	WaitTimeout      time.Duration     // Wait up to this long per provider for its controllers (0 does not wait)
+}
+
+// Init installs the Cluster API providers via clusterctl. Variables override clusterctl's variable
+// resolution (environment and clusterctl.yaml).
+func Init(ctx context.Context, opts InitOptions) error {
+	c, err := newClient(ctx, opts.ClusterctlConfig, opts.Variables)
 	if err != nil {
 		return err
 	}
 
-	opts := client.InitOptions{
-		CoreProvider:            core,
-		BootstrapProviders:      boots,
-		ControlPlaneProviders:   cps,
-		InfrastructureProviders: infras,
+	initOpts := client.InitOptions{
+		BootstrapProviders:      pinProviders(opts.Versions, string(clusterctlv1.BootstrapProviderType), opts.Bootstrap),
+		ControlPlaneProviders:   pinProviders(opts.Versions, string(clusterctlv1.ControlPlaneProviderType), opts.ControlPlane),
+		InfrastructureProviders: pinProviders(opts.Versions, string(clusterctlv1.InfrastructureProviderType), opts.Infrastructure),
+		IPAMProviders:           pinProviders(opts.Versions, string(clusterctlv1.IPAMProviderType), opts.IPAM),
+		AddonProviders:          pinProviders(opts.Versions, string(clusterctlv1.AddonProviderType), opts.Addon),
+		WaitProviders:           opts.WaitTimeout > 0,
+		WaitProviderTimeout:     opts.WaitTimeout,
 	}
-	if kubeconfig != "" {
-		opts.Kubeconfig = client.Kubeconfig{Path: kubeconfig}
+	if opts.Core != "" {
+		initOpts.CoreProvider = pinProviders(opts.Versions, string(clusterctlv1.CoreProviderType), []string{opts.Core})[0]
+	}
+	if opts.Kubeconfig != "" {
+		initOpts.Kubeconfig = client.Kubeconfig{Path: opts.Kubeconfig}
 	}
 
 	log.Debug().
-		Str("core", core).
-		Strs("bootstrap", boots).
-		Strs("controlPlane", cps).
-		Strs("infrastructure", infras).
+		Str("core", initOpts.CoreProvider).
+		Strs("bootstrap", initOpts.BootstrapProviders).
+		Strs("controlPlane", initOpts.ControlPlaneProviders).
+		Strs("infrastructure", initOpts.InfrastructureProviders).
+		Strs("variables", sortedKeys(opts.Variables)).
 		Msg("clusterctl: init")
 
-	_, err = c.Init(context.Background(), opts)
+	_, err = c.Init(ctx, initOpts)
 	return err
-
 }
 
-// Apply runs `kubectl apply -f` on the provided file or directory.
-func Apply(path, namespace string) error {
-	args := []string{"apply", "-f", path}
-	if namespace != "" {
-		args = append(args, "-n", namespace)
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
 	}
-	cmd := exec.Command("kubectl", args...)
-	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
-	return cmd.Run()
+	sort.Strings(keys)
+	return keys
 }
@@ -0,0 +1,132 @@
+package capix
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+)
+
+// newClient builds a clusterctl client whose configuration is read by a private viper instance,
+// so clusterctl never touches the global viper holding cctl's own settings. Its variable resolution
+// is pre-seeded with vars.
+func newClient(ctx context.Context, clusterConfig string, vars map[string]string) (client.Client, error) {
+	reader := &configReader{v: viper.New()}
+	if err := reader.Init(ctx, clusterConfig); err != nil {
+		return nil, fmt.Errorf("read clusterctl config: %w", err)
+	}
+	cfg, err := config.New(ctx, clusterConfig, config.InjectReader(reader))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range vars {
+		cfg.Variables().Set(k, v)
+	}
+	return client.New(ctx, clusterConfig, client.InjectConfig(cfg))
+}
+
+// configReader implements the clusterctl config.Reader like clusterctl's own viper reader
+// (environment variables, then clusterctl.yaml), on its own viper instance.
+type configReader struct {
+	v *viper.Viper
+}
+
+// Init reads path, a local file or an http(s) URL; an empty path reads clusterctl.yaml from
+// $XDG_CONFIG_HOME/cluster-api or $HOME/.cluster-api when present.
+func (r *configReader) Init(ctx context.Context, path string) error {
+	r.v.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	r.v.AllowEmptyEnv(true)
+	r.v.AutomaticEnv()
+
+	if path == "" {
+		dirs := defaultConfigDirs()
+		if !hasDefaultConfig(dirs) {
+			return nil
+		}
+		r.v.SetConfigName(config.ConfigName)
+		for _, dir := range dirs {
+			r.v.AddConfigPath(dir)
+		}
+		return r.v.ReadInConfig()
+	}
+
+	if u, err := url.Parse(path); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		raw, err := download(ctx, u.String())
+		if err != nil {
+			return err
+		}
+		ext := strings.TrimPrefix(filepath.Ext(u.Path), ".")
+		if ext == "" {
+			ext = "yaml"
+		}
+		r.v.SetConfigType(ext)
+		return r.v.ReadConfig(bytes.NewReader(raw))
+	}
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	r.v.SetConfigFile(path)
+	return r.v.ReadInConfig()
+}
+
+func (r *configReader) Get(key string) (string, error) {
+	if r.v.Get(key) == nil {
+		return "", fmt.Errorf("variable %q is not set in the environment or the clusterctl config", key)
+	}
+	return r.v.GetString(key), nil
+}
+
+func (r *configReader) Set(key, value string) {
+	r.v.Set(key, value)
+}
+
+func (r *configReader) UnmarshalKey(key string, value any) error {
+	return r.v.UnmarshalKey(key, value)
+}
+
+func defaultConfigDirs() []string {
+	var dirs []string
+	if dir, err := os.UserConfigDir(); err == nil {
+		dirs = append(dirs, filepath.Join(dir, config.ConfigFolderXDG))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, config.ConfigFolder))
+	}
+	return dirs
+}
+
+func hasDefaultConfig(dirs []string) bool {
+	for _, dir := range dirs {
+		for _, ext := range viper.SupportedExts {
+			if _, err := os.Stat(filepath.Join(dir, config.ConfigName+"."+ext)); err == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func download(ctx context.Context, rawURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download %s: %s", rawURL, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,36 @@
+package capix
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestConfigReaderIsolated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clusterctl.yaml")
+	if err := os.WriteFile(path, []byte("PROXMOX_URL: https://pve.example:8006\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	viper.Set("proxmox.url", "https://cctl.example:8006")
+	t.Cleanup(viper.Reset)
+
+	r := &configReader{v: viper.New()}
+	if err := r.Init(context.Background(), path); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := r.Get("PROXMOX_URL"); err != nil || got != "https://pve.example:8006" {
+		t.Fatalf("Get(PROXMOX_URL) = %q, %v", got, err)
+	}
+	if _, err := r.Get("UNSET_VARIABLE"); err == nil {
+		t.Fatal("Get(UNSET_VARIABLE) succeeded, want an error")
+	}
+	if viper.ConfigFileUsed() != "" || viper.IsSet("PROXMOX_URL") {
+		t.Fatal("clusterctl config leaked into the global viper")
+	}
+	if got := viper.GetString("proxmox.url"); got != "https://cctl.example:8006" {
+		t.Fatalf("global proxmox.url = %q, want it untouched", got)
+	}
+}
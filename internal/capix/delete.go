@@ -0,0 +1,125 @@
+package capix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// MachineRef is a ProxmoxMachine of a cluster and the VM backing it.
+type MachineRef struct {
+	Name string
+	VMID int64 // 0 until the provider has created the VM
+}
+
+// DeleteCluster deletes the Cluster and waits until it, its Machines and its ProxmoxMachines are gone.
+// The ProxmoxMachines found before the deletion are returned so leftover VMs can be checked for.
+func DeleteCluster(ctx context.Context, opts WaitOptions) ([]MachineRef, error) {
+	c, err := newKubeClient(opts.Kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	return deleteCluster(ctx, c, opts)
+}
+
+func deleteCluster(ctx context.Context, c ctrlclient.Client, opts WaitOptions) ([]MachineRef, error) {
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultWaitTimeout
+	}
+	if opts.Interval == 0 {
+		opts.Interval = defaultWaitInterval
+	}
+
+	machines, err := proxmoxMachines(ctx, c, opts.Namespace, opts.Cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	cluster := newObject(ClusterGVK)
+	cluster.SetNamespace(opts.Namespace)
+	cluster.SetName(opts.Cluster)
+	if err := c.Delete(ctx, cluster); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return machines, fmt.Errorf("delete cluster %s: %w", opts.Cluster, err)
+		}
+		log.Warn().Str("cluster", opts.Cluster).Msg("Cluster not found; waiting for remaining machines")
+	} else {
+		log.Info().Str("cluster", opts.Cluster).Str("namespace", opts.Namespace).Msg("Deleting workload cluster")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+
+	var last string
+	for {
+		remaining, err := remainingObjects(ctx, c, opts)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			log.Warn().Err(err).Msg("Failed to read cluster objects; retrying")
+		case err == nil && remaining == "":
+			log.Info().Str("cluster", opts.Cluster).Msg("Workload cluster deleted")
+			return machines, nil
+		case err == nil && remaining != last:
+			log.Info().Str("cluster", opts.Cluster).Str("remaining", remaining).Msg("Waiting for deletion")
+			last = remaining
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return machines, ctx.Err()
+			}
+			return machines, fmt.Errorf("timed out after %s waiting for deletion of cluster %s (remaining: %s)",
+				opts.Timeout, opts.Cluster, last)
+		case <-ticker.C:
+		}
+	}
+}
+
+// remainingObjects summarizes the objects of the cluster that still exist, e.g. "Cluster, 2 Machines".
+// It returns an empty string once everything is gone.
+func remainingObjects(ctx context.Context, c ctrlclient.Client, opts WaitOptions) (string, error) {
+	var parts []string
+	cluster := newObject(ClusterGVK)
+	err := c.Get(ctx, ctrlclient.ObjectKey{Namespace: opts.Namespace, Name: opts.Cluster}, cluster)
+	switch {
+	case err == nil:
+		parts = append(parts, "Cluster")
+	case !apierrors.IsNotFound(err):
+		return "", err
+	}
+
+	selector := ctrlclient.MatchingLabels{clusterNameLabel: opts.Cluster}
+	for _, gvk := range []schema.GroupVersionKind{MachineGVK, ProxmoxMachineGVK} {
+		list := newList(gvk)
+		if err := c.List(ctx, list, ctrlclient.InNamespace(opts.Namespace), selector); err != nil {
+			return "", err
+		}
+		if n := len(list.Items); n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %ss", n, gvk.Kind))
+		}
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+func proxmoxMachines(ctx context.Context, c ctrlclient.Client, namespace, cluster string) ([]MachineRef, error) {
+	list := newList(ProxmoxMachineGVK)
+	if err := c.List(ctx, list, ctrlclient.InNamespace(namespace), ctrlclient.MatchingLabels{clusterNameLabel: cluster}); err != nil {
+		return nil, fmt.Errorf("list proxmoxmachines: %w", err)
+	}
+	refs := make([]MachineRef, 0, len(list.Items))
+	for i := range list.Items {
+		pm := &list.Items[i]
+		refs = append(refs, MachineRef{Name: pm.GetName(), VMID: nestedInt(pm, "spec", "virtualMachineID")})
+	}
+	return refs, nil
+}
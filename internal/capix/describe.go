@@ -0,0 +1,197 @@
+package capix
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/tree"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DescribeNode is one object in the cluster status tree.
+type DescribeNode struct {
+	Kind      string         `json:"kind"`
+	Name      string         `json:"name"`
+	Namespace string         `json:"namespace,omitempty"`
+	Role      string         `json:"role,omitempty"` // e.g. ControlPlane, ClusterInfrastructure
+	Ready     string         `json:"ready,omitempty"`
+	Severity  string         `json:"severity,omitempty"`
+	Reason    string         `json:"reason,omitempty"`
+	Message   string         `json:"message,omitempty"`
+	Virtual   bool           `json:"virtual,omitempty"` // Grouping node added by clusterctl (e.g. Workers)
+	Deleting  bool           `json:"deleting,omitempty"`
+	Machine   *MachineInfo   `json:"machine,omitempty"`
+	Children  []DescribeNode `json:"children,omitempty"`
+}
+
+// MachineInfo links a Machine to its ProxmoxMachine and the VM backing it.
+type MachineInfo struct {
+	ProxmoxMachine string   `json:"proxmoxMachine,omitempty"`
+	VMID           int64    `json:"vmid,omitempty"`
+	ProxmoxNode    string   `json:"proxmoxNode,omitempty"`
+	Node           string   `json:"node,omitempty"` // Kubernetes node
+	Addresses      []string `json:"addresses,omitempty"`
+}
+
+// Describe returns the status tree of the cluster: the Cluster with its infrastructure, the control plane,
+// the MachineDeployments and the Machines, which carry their ProxmoxMachine, VMID, node and IPs.
+func Describe(ctx context.Context, clusterctlConfig, kubeconfig, namespace, cluster string) (*DescribeNode, error) {
+	c, err := newClient(ctx, clusterctlConfig, nil)
+	if err != nil {
+		return nil, err
+	}
+	objTree, err := c.DescribeCluster(ctx, client.DescribeClusterOptions{
+		Kubeconfig:  client.Kubeconfig{Path: kubeconfig},
+		Namespace:   namespace,
+		ClusterName: cluster,
+		V1Beta1:     true, // v1beta1 conditions carry the severity reported by the providers
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describe cluster %s: %w", cluster, err)
+	}
+
+	kc, err := newKubeClient(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	machines, err := machineInfos(ctx, kc, namespace, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	root := describeNode(objTree, objTree.GetRoot(), machines)
+	return &root, nil
+}
+
+func describeNode(objTree *tree.ObjectTree, obj ctrlclient.Object, machines map[string]*MachineInfo) DescribeNode {
+	node := DescribeNode{
+		Kind:      objectKind(obj),
+		Name:      obj.GetName(),
+		Namespace: obj.GetNamespace(),
+		Role:      tree.GetMetaName(obj),
+		Deleting:  !obj.GetDeletionTimestamp().IsZero(),
+	}
+	if tree.IsVirtualObject(obj) {
+		node.Virtual, node.Namespace = true, ""
+	}
+
+	if c := tree.GetV1Beta1ReadyCondition(obj); c != nil {
+		node.Ready, node.Severity, node.Reason, node.Message = string(c.Status), string(c.Severity), c.Reason, c.Message
+	} else if c := tree.GetReadyCondition(obj); c != nil {
+		node.Ready, node.Reason, node.Message = string(c.Status), c.Reason, c.Message
+	}
+	if node.Kind == MachineGVK.Kind {
+		node.Machine = machines[obj.GetName()]
+	}
+
+	for _, child := range objTree.GetObjectsByParent(obj.GetUID()) {
+		node.Children = append(node.Children, describeNode(objTree, child, machines))
+	}
+	return node
+}
+
+func objectKind(obj ctrlclient.Object) string {
+	if kind := obj.GetObjectKind().GroupVersionKind().Kind; kind != "" {
+		return kind
+	}
+	t := reflect.TypeOf(obj)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+// machineInfos indexes the Machines of the cluster by name, joined with their ProxmoxMachines.
+func machineInfos(ctx context.Context, c ctrlclient.Client, namespace, cluster string) (map[string]*MachineInfo, error) {
+	selector := ctrlclient.MatchingLabels{clusterNameLabel: cluster}
+
+	pms := newList(ProxmoxMachineGVK)
+	if err := c.List(ctx, pms, ctrlclient.InNamespace(namespace), selector); err != nil {
+		return nil, fmt.Errorf("list proxmoxmachines: %w", err)
+	}
+	byName := make(map[string]*unstructured.Unstructured, len(pms.Items))
+	for i := range pms.Items {
+		byName[pms.Items[i].GetName()] = &pms.Items[i]
+	}
+
+	machines := newList(MachineGVK)
+	if err := c.List(ctx, machines, ctrlclient.InNamespace(namespace), selector); err != nil {
+		return nil, fmt.Errorf("list machines: %w", err)
+	}
+	infos := make(map[string]*MachineInfo, len(machines.Items))
+	for i := range machines.Items {
+		m := &machines.Items[i]
+		info := &MachineInfo{
+			Node:      nestedString(m, "status", "nodeRef", "name"),
+			Addresses: machineAddresses(m),
+		}
+		if pm := byName[nestedString(m, "spec", "infrastructureRef", "name")]; pm != nil {
+			info.ProxmoxMachine = pm.GetName()
+			info.VMID = nestedInt(pm, "spec", "virtualMachineID")
+			info.ProxmoxNode = nestedString(pm, "status", "proxmoxNode")
+		}
+		infos[m.GetName()] = info
+	}
+	return infos, nil
+}
+
+func machineAddresses(m *unstructured.Unstructured) []string {
+	raw, _, _ := unstructured.NestedSlice(m.Object, "status", "addresses")
+	var out []string
+	for _, item := range raw {
+		addr, _ := item.(map[string]any)
+		t, _ := addr["type"].(string)
+		v, _ := addr["address"].(string)
+		if v != "" && (t == "InternalIP" || t == "ExternalIP") {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// WriteTree prints the status tree in the style of `clusterctl describe cluster`, with
+// VMID, node and IP columns for machines.
+func WriteTree(w io.Writer, root *DescribeNode) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tREADY\tSEVERITY\tREASON\tVMID\tPVE NODE\tNODE\tIP")
+	writeTreeRow(tw, *root, "", "")
+	return tw.Flush()
+}
+
+func writeTreeRow(w io.Writer, n DescribeNode, prefix, childPrefix string) {
+	name := n.Kind + "/" + n.Name
+	switch {
+	case n.Virtual && n.Role != "":
+		name = n.Role
+	case n.Virtual:
+		name = n.Name
+	case n.Role != "":
+		name = n.Role + " - " + name
+	}
+	if n.Deleting {
+		name = "!! DELETED !! " + name
+	}
+
+	var vmid, pveNode, node, ips string
+	if m := n.Machine; m != nil {
+		if m.VMID != 0 {
+			vmid = fmt.Sprint(m.VMID)
+		}
+		pveNode, node, ips = m.ProxmoxNode, m.Node, strings.Join(m.Addresses, ",")
+	}
+	fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", prefix, name, n.Ready, n.Severity, n.Reason, vmid, pveNode, node, ips)
+
+	for i, child := range n.Children {
+		if i == len(n.Children)-1 {
+			writeTreeRow(w, child, childPrefix+"└─", childPrefix+"  ")
+		} else {
+			writeTreeRow(w, child, childPrefix+"├─", childPrefix+"│ ")
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package capix
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// serverPopulatedFields are dropped from both sides before objects are compared.
+var serverPopulatedFields = [][]string{
+	{"status"},
+	{"metadata", "managedFields"},
+	{"metadata", "resourceVersion"},
+	{"metadata", "uid"},
+	{"metadata", "generation"},
+	{"metadata", "creationTimestamp"},
+	{"metadata", "selfLink"},
+	{"metadata", "annotations", lastAppliedAnnotation},
+}
+
+// DiffResult is the difference between the live object and the object after applying the manifest.
+type DiffResult struct {
+	ApplyResult
+	Diff string `json:"diff,omitempty"` // Unified diff; empty when the object is unchanged
+}
+
+// Diff compares the manifests in path with the live objects. The desired state is computed with a
+// server-side dry-run apply, so defaulting and admission webhooks are taken into account.
+func Diff(ctx context.Context, path string, opts ApplyOptions) ([]DiffResult, error) {
+	objs, err := LoadManifests(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := newKubeClient(opts.Kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	opts.DryRun = DryRunServer
+
+	results := make([]DiffResult, 0, len(objs))
+	for _, obj := range objs {
+		if err := setNamespace(c, obj, opts.Namespace); err != nil {
+			return results, err
+		}
+		result, err := diffObject(ctx, c, obj, opts)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func diffObject(ctx context.Context, c ctrlclient.Client, obj *unstructured.Unstructured, opts ApplyOptions) (DiffResult, error) {
+	result := DiffResult{ApplyResult: ApplyResult{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+		DryRun:     DryRunServer,
+	}}
+
+	live, err := getLive(ctx, c, obj)
+	if err != nil {
+		return result, err
+	}
+	merged, err := serverApply(ctx, c, obj, opts)
+	if err != nil {
+		return result, err
+	}
+
+	var before map[string]any
+	if live != nil {
+		before = normalize(live)
+	}
+	after := normalize(merged)
+	switch {
+	case live == nil:
+		result.Action = ActionCreated
+	case reflect.DeepEqual(before, after):
+		result.Action = ActionUnchanged
+		return result, nil
+	default:
+		result.Action = ActionConfigured
+	}
+
+	result.Diff, err = unifiedDiff(describeObject(obj), before, after)
+	return result, err
+}
+
+// normalize returns a copy of the object without server-populated fields.
+func normalize(obj *unstructured.Unstructured) map[string]any {
+	out := obj.DeepCopy()
+	for _, field := range serverPopulatedFields {
+		unstructured.RemoveNestedField(out.Object, field...)
+	}
+	if annotations := out.GetAnnotations(); annotations != nil && len(annotations) == 0 {
+		unstructured.RemoveNestedField(out.Object, "metadata", "annotations")
+	}
+	return out.Object
+}
+
+func unifiedDiff(name string, before, after map[string]any) (string, error) {
+	var a, b []byte
+	var err error
+	if before != nil {
+		if a, err = yaml.Marshal(before); err != nil {
+			return "", fmt.Errorf("encode live %s: %w", name, err)
+		}
+	}
+	if b, err = yaml.Marshal(after); err != nil {
+		return "", fmt.Errorf("encode merged %s: %w", name, err)
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(a)),
+		B:        difflib.SplitLines(string(b)),
+		FromFile: "live/" + name,
+		ToFile:   "merged/" + name,
+		Context:  3,
+	})
+}
+
+// isSubset reports whether every field set in want has the same value in have. Lists must have the
+// same length and each element of want must be a subset of the element at the same index in have,
+// so fields the server adds to list items (defaults such as imagePullPolicy) are ignored.
+func isSubset(want, have any) bool {
+	switch w := want.(type) {
+	case map[string]any:
+		h, ok := have.(map[string]any)
+		if !ok {
+			return false
+		}
+		for k, v := range w {
+			if !isSubset(v, h[k]) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		h, ok := have.([]any)
+		if !ok || len(w) != len(h) {
+			return false
+		}
+		for i := range w {
+			if !isSubset(w[i], h[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(want, have)
+	}
+}
@@ -0,0 +1,41 @@
+package capix
+
+import "testing"
+
+func TestIsSubset(t *testing.T) {
+	container := func(fields map[string]any) map[string]any {
+		c := map[string]any{"name": "capmox", "image": "capmox:v0.7.4"}
+		for k, v := range fields {
+			c[k] = v
+		}
+		return c
+	}
+	tests := []struct {
+		name       string
+		want, have any
+		ok         bool
+	}{
+		{"equal scalars", "a", "a", true},
+		{"different scalars", int64(1), int64(2), false},
+		{"missing field", map[string]any{"a": "x"}, map[string]any{}, false},
+		{"extra field in have", map[string]any{"a": "x"}, map[string]any{"a": "x", "b": "y"}, true},
+		{"nested extra field", map[string]any{"spec": map[string]any{"replicas": int64(3)}},
+			map[string]any{"spec": map[string]any{"replicas": int64(3), "strategy": "RollingUpdate"}}, true},
+		{"server-extended list items", []any{container(nil)},
+			[]any{container(map[string]any{"imagePullPolicy": "IfNotPresent"})}, true},
+		{"changed list item", []any{container(map[string]any{"image": "capmox:v0.7.5"})},
+			[]any{container(map[string]any{"imagePullPolicy": "IfNotPresent"})}, false},
+		{"extra list item in have", []any{"a"}, []any{"a", "b"}, false},
+		{"missing list item in have", []any{"a", "b"}, []any{"a"}, false},
+		{"reordered list", []any{"a", "b"}, []any{"b", "a"}, false},
+		{"list against map", []any{"a"}, map[string]any{"a": "a"}, false},
+		{"empty list against missing", []any{}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isSubset(tt.want, tt.have); got != tt.ok {
+				t.Fatalf("isSubset(%v, %v) = %t, want %t", tt.want, tt.have, got, tt.ok)
+			}
+		})
+	}
+}
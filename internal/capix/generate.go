@@ -0,0 +1,316 @@
+package capix
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/zerodi/cctl/internal/configx"
+
+	"sigs.k8s.io/yaml"
+)
+
+// API versions of the objects in a generated cluster bundle.
+const (
+	ClusterAPIVersion        = "cluster.x-k8s.io/v1beta1"
+	ControlPlaneAPIVersion   = "controlplane.cluster.x-k8s.io/v1alpha3"
+	BootstrapAPIVersion      = "bootstrap.cluster.x-k8s.io/v1alpha3"
+	InfrastructureAPIVersion = "infrastructure.cluster.x-k8s.io/v1alpha1"
+)
+
+const (
+	clusterNameLabel    = "cluster.x-k8s.io/cluster-name"
+	deploymentNameLabel = "cluster.x-k8s.io/deployment-name"
+	controlPlaneLabel   = "cluster.x-k8s.io/control-plane"
+)
+
+// ControlPlaneName returns the TalosControlPlane name of the cluster.
+func ControlPlaneName(cluster string) string { return cluster + "-control-plane" }
+
+// MachineDeploymentName returns the MachineDeployment name of a worker pool.
+func MachineDeploymentName(cluster, pool string) string { return cluster + "-" + pool }
+
+// MachineNamePattern matches the names of the machines of a generated cluster, which CAPMOX also
+// gives their VMs: <cluster>-control-plane-<suffix> for the control plane and
+// <cluster>-<pool>-<hash>-<suffix> for a worker pool. Machines of another cluster whose name merely
+// starts with <cluster>- (e.g. prod-eu for prod) do not match.
+func MachineNamePattern(cluster string, pools []string) *regexp.Regexp {
+	alts := []string{regexp.QuoteMeta(ControlPlaneName(cluster)) + `-[a-z0-9]{5}`}
+	for _, pool := range pools {
+		alts = append(alts, regexp.QuoteMeta(MachineDeploymentName(cluster, pool))+`-[a-z0-9]+-[a-z0-9]{5}`)
+	}
+	return regexp.MustCompile(`^(?:` + strings.Join(alts, "|") + `)$`)
+}
+
+type object = map[string]any
+
+// Generate renders the cluster bundle (Cluster, ProxmoxCluster, ProxmoxMachineTemplates,
+// TalosControlPlane, TalosConfigTemplates, MachineDeployments and MachineHealthChecks) as multi-document YAML.
+func Generate(settings configx.ClusterSettings, spec configx.ClusterSpec) ([]byte, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	g := generator{name: settings.Name, namespace: settings.Namespace, spec: spec}
+
+	objects := []object{g.cluster(), g.proxmoxCluster()}
+	objects = append(objects,
+		g.machineTemplate(ControlPlaneName(g.name)+"-template", spec.ControlPlane),
+		g.controlPlane(),
+	)
+	for _, pool := range spec.Workers {
+		objects = append(objects,
+			g.machineTemplate(MachineDeploymentName(g.name, pool.Name)+"-template", pool),
+			g.workerConfig(pool),
+			g.machineDeployment(pool),
+		)
+	}
+	if !spec.HealthChecks.Disabled {
+		objects = append(objects, g.controlPlaneHealthCheck())
+		for _, pool := range spec.Workers {
+			objects = append(objects, g.workerHealthCheck(pool))
+		}
+	}
+
+	var out bytes.Buffer
+	for _, obj := range objects {
+		raw, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, fmt.Errorf("encode %s: %w", obj["kind"], err)
+		}
+		out.WriteString("---\n")
+		out.Write(raw)
+	}
+	return out.Bytes(), nil
+}
+
+type generator struct {
+	name      string
+	namespace string
+	spec      configx.ClusterSpec
+}
+
+func (g generator) meta(name string) object {
+	return object{"name": name, "namespace": g.namespace}
+}
+
+func ref(apiVersion, kind, name string) object {
+	return object{"apiVersion": apiVersion, "kind": kind, "name": name}
+}
+
+func (g generator) cluster() object {
+	return object{
+		"apiVersion": ClusterAPIVersion,
+		"kind":       "Cluster",
+		"metadata":   g.meta(g.name),
+		"spec": object{
+			"clusterNetwork": object{
+				"pods":     object{"cidrBlocks": g.spec.Network.PodCIDRs},
+				"services": object{"cidrBlocks": g.spec.Network.ServiceCIDRs},
+			},
+			"controlPlaneRef":   ref(ControlPlaneAPIVersion, "TalosControlPlane", ControlPlaneName(g.name)),
+			"infrastructureRef": ref(InfrastructureAPIVersion, "ProxmoxCluster", g.name),
+		},
+	}
+}
+
+func (g generator) proxmoxCluster() object {
+	pool := g.spec.Network.IPv4Pool
+	return object{
+		"apiVersion": InfrastructureAPIVersion,
+		"kind":       "ProxmoxCluster",
+		"metadata":   g.meta(g.name),
+		"spec": object{
+			"allowedNodes": g.spec.Proxmox.AllowedNodes,
+			"controlPlaneEndpoint": object{
+				"host": g.spec.ControlPlaneEndpoint.Host,
+				"port": g.spec.ControlPlaneEndpoint.Port,
+			},
+			"dnsServers": g.spec.Network.DNSServers,
+			"ipv4Config": object{
+				"addresses": pool.Addresses,
+				"gateway":   pool.Gateway,
+				"prefix":    pool.Prefix,
+			},
+			"schedulerHints": object{"memoryAdjustment": 0},
+		},
+	}
+}
+
+func (g generator) machineTemplate(name string, pool configx.MachinePoolSpec) object {
+	return object{
+		"apiVersion": InfrastructureAPIVersion,
+		"kind":       "ProxmoxMachineTemplate",
+		"metadata":   g.meta(name),
+		"spec": object{
+			"template": object{
+				"spec": object{
+					"disks": object{
+						"bootVolume": object{"disk": "scsi0", "sizeGb": pool.DiskGB},
+					},
+					"format":    "qcow2",
+					"full":      true,
+					"memoryMiB": pool.MemoryMiB,
+					"network": object{
+						"default": object{"bridge": g.spec.Proxmox.Bridge, "model": "virtio"},
+					},
+					"numCores":         pool.Cores,
+					"numSockets":       pool.Sockets,
+					"sourceNode":       g.spec.Proxmox.SourceNode,
+					"templateID":       g.spec.Proxmox.TemplateID,
+					"checks":           object{"skipCloudInitStatus": true},
+					"metadataSettings": object{"providerIDInjection": true},
+				},
+			},
+		},
+	}
+}
+
+func (g generator) controlPlane() object {
+	name := ControlPlaneName(g.name)
+	patches := append(g.commonPatches(),
+		patch("/machine/network/interfaces", []object{{
+			"interface": g.spec.Network.Interface,
+			"dhcp":      false,
+			"vip":       object{"ip": g.spec.ControlPlaneEndpoint.Host},
+		}}),
+		patch("/machine/features/kubePrism", object{"enabled": true, "port": 7445}),
+	)
+
+	infra := ref(InfrastructureAPIVersion, "ProxmoxMachineTemplate", name+"-template")
+	infra["namespace"] = g.namespace
+	return object{
+		"apiVersion": ControlPlaneAPIVersion,
+		"kind":       "TalosControlPlane",
+		"metadata":   g.meta(name),
+		"spec": object{
+			"version":                g.spec.KubernetesVersion,
+			"replicas":               g.spec.ControlPlane.Replicas,
+			"infrastructureTemplate": infra,
+			"controlPlaneConfig": object{
+				"controlplane": object{
+					"generateType":  "controlplane",
+					"configPatches": patches,
+				},
+			},
+		},
+	}
+}
+
+func (g generator) workerConfig(pool configx.MachinePoolSpec) object {
+	patches := append(g.commonPatches(),
+		patch("/machine/network/interfaces", []object{{
+			"interface": g.spec.Network.Interface,
+			"dhcp":      true,
+			"routes": []object{{
+				"network": "0.0.0.0/0",
+				"gateway": g.spec.Network.IPv4Pool.Gateway,
+			}},
+		}}),
+	)
+	return object{
+		"apiVersion": BootstrapAPIVersion,
+		"kind":       "TalosConfigTemplate",
+		"metadata":   g.meta(MachineDeploymentName(g.name, pool.Name) + "-config"),
+		"spec": object{
+			"template": object{
+				"spec": object{
+					"generateType":  "worker",
+					"configPatches": patches,
+				},
+			},
+		},
+	}
+}
+
+// commonPatches prepare nodes for Cilium (no Talos CNI, no kube-proxy) and leave discovery to CAPI.
+func (g generator) commonPatches() []object {
+	return []object{
+		patch("/machine/install/extraKernelArgs", []string{"net.ifnames=0"}),
+		patch("/cluster/network/cni", object{"name": "none"}),
+		patch("/cluster/proxy", object{"disabled": true}),
+		patch("/machine/features/hostDNS", object{"enabled": true, "forwardKubeDNSToHost": true}),
+		patch("/cluster/discovery", object{
+			"enabled": false,
+			"registries": object{
+				"kubernetes": object{"disabled": true},
+				"service":    object{"disabled": true},
+			},
+		}),
+	}
+}
+
+func patch(path string, value any) object {
+	return object{"op": "add", "path": path, "value": value}
+}
+
+func (g generator) machineDeployment(pool configx.MachinePoolSpec) object {
+	name := MachineDeploymentName(g.name, pool.Name)
+	labels := object{clusterNameLabel: g.name, deploymentNameLabel: name}
+
+	bootstrap := ref(BootstrapAPIVersion, "TalosConfigTemplate", name+"-config")
+	bootstrap["namespace"] = g.namespace
+	infra := ref(InfrastructureAPIVersion, "ProxmoxMachineTemplate", name+"-template")
+	infra["namespace"] = g.namespace
+
+	meta := g.meta(name)
+	meta["labels"] = object{clusterNameLabel: g.name}
+	return object{
+		"apiVersion": ClusterAPIVersion,
+		"kind":       "MachineDeployment",
+		"metadata":   meta,
+		"spec": object{
+			"clusterName": g.name,
+			"replicas":    pool.Replicas,
+			"selector":    object{"matchLabels": labels},
+			"template": object{
+				"metadata": object{
+					"labels": object{
+						clusterNameLabel:               g.name,
+						deploymentNameLabel:            name,
+						"node-role.kubernetes.io/node": "",
+					},
+				},
+				"spec": object{
+					"clusterName":       g.name,
+					"version":           g.spec.KubernetesVersion,
+					"bootstrap":         object{"configRef": bootstrap},
+					"infrastructureRef": infra,
+				},
+			},
+		},
+	}
+}
+
+func (g generator) controlPlaneHealthCheck() object {
+	return g.healthCheck(ControlPlaneName(g.name)+"-unhealthy", 1, object{
+		"matchExpressions": []object{{"key": controlPlaneLabel, "operator": "Exists"}},
+	})
+}
+
+func (g generator) workerHealthCheck(pool configx.MachinePoolSpec) object {
+	name := MachineDeploymentName(g.name, pool.Name)
+	return g.healthCheck(name+"-unhealthy", g.spec.HealthChecks.WorkerMaxUnhealthy, object{
+		"matchLabels": object{deploymentNameLabel: name},
+	})
+}
+
+func (g generator) healthCheck(name string, maxUnhealthy any, selector object) object {
+	hc := g.spec.HealthChecks
+	timeout := hc.UnhealthyTimeout.String()
+	return object{
+		"apiVersion": ClusterAPIVersion,
+		"kind":       "MachineHealthCheck",
+		"metadata":   g.meta(name),
+		"spec": object{
+			"clusterName":        g.name,
+			"maxUnhealthy":       maxUnhealthy,
+			"nodeStartupTimeout": hc.NodeStartupTimeout.String(),
+			"selector":           selector,
+			"unhealthyConditions": []object{
+				{"type": "Ready", "status": "Unknown", "timeout": timeout},
+				{"type": "Ready", "status": "False", "timeout": timeout},
+			},
+		},
+	}
+}
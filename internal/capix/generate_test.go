@@ -0,0 +1,218 @@
+package capix
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/zerodi/cctl/internal/configx"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+func testSpec() configx.ClusterSpec {
+	return configx.ClusterSpec{
+		KubernetesVersion:    "v1.34.0",
+		ControlPlaneEndpoint: configx.EndpointSpec{Host: "192.168.100.200", Port: 6443},
+		Network: configx.NetworkSpec{
+			PodCIDRs:     []string{"10.244.0.0/16"},
+			ServiceCIDRs: []string{"10.96.0.0/12"},
+			DNSServers:   []string{"8.8.8.8"},
+			Interface:    "eth0",
+			IPv4Pool: configx.IPPoolSpec{
+				Addresses: []string{"192.168.100.210-192.168.100.219"},
+				Gateway:   "192.168.100.1",
+				Prefix:    24,
+			},
+		},
+		Proxmox:      configx.ProxmoxSpec{AllowedNodes: []string{"pve1"}, SourceNode: "pve1", TemplateID: 900, Bridge: "vmbr0"},
+		ControlPlane: configx.MachinePoolSpec{Replicas: 3, Cores: 2, Sockets: 1, MemoryMiB: 4096, DiskGB: 40},
+		Workers: []configx.MachinePoolSpec{
+			{Name: "workers", Replicas: 2, Cores: 4, Sockets: 1, MemoryMiB: 4096, DiskGB: 80},
+			{Name: "gpu", Replicas: 1, Cores: 8, Sockets: 1, MemoryMiB: 16384, DiskGB: 120},
+		},
+		HealthChecks: configx.HealthCheckSpec{
+			UnhealthyTimeout:   5 * time.Minute,
+			NodeStartupTimeout: 15 * time.Minute,
+			WorkerMaxUnhealthy: "40%",
+		},
+	}
+}
+
+// decodeBundle splits the multi-document output of Generate into objects.
+func decodeBundle(t *testing.T, raw []byte) []*unstructured.Unstructured {
+	t.Helper()
+	var objs []*unstructured.Unstructured
+	for _, doc := range bytes.Split(raw, []byte("---\n")) {
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		js, err := yaml.YAMLToJSON(doc)
+		if err != nil {
+			t.Fatalf("decode generated document: %v", err)
+		}
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(js); err != nil {
+			t.Fatalf("decode generated document: %v", err)
+		}
+		objs = append(objs, obj)
+	}
+	return objs
+}
+
+func TestGenerate(t *testing.T) {
+	settings := configx.ClusterSettings{Name: "prod", Namespace: "default"}
+
+	tests := []struct {
+		name string
+		edit func(spec *configx.ClusterSpec)
+		want []string // kind/name in output order
+	}{
+		{
+			name: "two pools",
+			want: []string{
+				"Cluster/prod",
+				"ProxmoxCluster/prod",
+				"ProxmoxMachineTemplate/prod-control-plane-template",
+				"TalosControlPlane/prod-control-plane",
+				"ProxmoxMachineTemplate/prod-workers-template",
+				"TalosConfigTemplate/prod-workers-config",
+				"MachineDeployment/prod-workers",
+				"ProxmoxMachineTemplate/prod-gpu-template",
+				"TalosConfigTemplate/prod-gpu-config",
+				"MachineDeployment/prod-gpu",
+				"MachineHealthCheck/prod-control-plane-unhealthy",
+				"MachineHealthCheck/prod-workers-unhealthy",
+				"MachineHealthCheck/prod-gpu-unhealthy",
+			},
+		},
+		{
+			name: "health checks disabled",
+			edit: func(s *configx.ClusterSpec) {
+				s.Workers = s.Workers[:1]
+				s.HealthChecks.Disabled = true
+			},
+			want: []string{
+				"Cluster/prod",
+				"ProxmoxCluster/prod",
+				"ProxmoxMachineTemplate/prod-control-plane-template",
+				"TalosControlPlane/prod-control-plane",
+				"ProxmoxMachineTemplate/prod-workers-template",
+				"TalosConfigTemplate/prod-workers-config",
+				"MachineDeployment/prod-workers",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := testSpec()
+			if tt.edit != nil {
+				tt.edit(&spec)
+			}
+			raw, err := Generate(settings, spec)
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			objs := decodeBundle(t, raw)
+
+			var got []string
+			for _, obj := range objs {
+				got = append(got, obj.GetKind()+"/"+obj.GetName())
+				if obj.GetNamespace() != settings.Namespace {
+					t.Errorf("%s/%s namespace = %q, want %q", obj.GetKind(), obj.GetName(), obj.GetNamespace(), settings.Namespace)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("Generate() objects = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateReferences(t *testing.T) {
+	raw, err := Generate(configx.ClusterSettings{Name: "prod", Namespace: "default"}, testSpec())
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	byName := make(map[string]*unstructured.Unstructured)
+	for _, obj := range decodeBundle(t, raw) {
+		byName[obj.GetKind()+"/"+obj.GetName()] = obj
+	}
+
+	cluster := byName["Cluster/prod"]
+	if got := nestedString(cluster, "spec", "controlPlaneRef", "name"); got != "prod-control-plane" {
+		t.Errorf("Cluster controlPlaneRef = %q, want prod-control-plane", got)
+	}
+	if got := nestedString(cluster, "spec", "infrastructureRef", "name"); got != "prod" {
+		t.Errorf("Cluster infrastructureRef = %q, want prod", got)
+	}
+
+	cp := byName["TalosControlPlane/prod-control-plane"]
+	if got := nestedInt(cp, "spec", "replicas"); got != 3 {
+		t.Errorf("TalosControlPlane replicas = %d, want 3", got)
+	}
+	if got := nestedString(cp, "spec", "version"); got != "v1.34.0" {
+		t.Errorf("TalosControlPlane version = %q, want v1.34.0", got)
+	}
+	if got := nestedString(cp, "spec", "infrastructureTemplate", "name"); got != "prod-control-plane-template" {
+		t.Errorf("TalosControlPlane infrastructureTemplate = %q, want prod-control-plane-template", got)
+	}
+
+	for _, pool := range []struct {
+		name     string
+		replicas int64
+		cores    int64
+	}{{"workers", 2, 4}, {"gpu", 1, 8}} {
+		md := byName["MachineDeployment/prod-"+pool.name]
+		if got := nestedInt(md, "spec", "replicas"); got != pool.replicas {
+			t.Errorf("MachineDeployment prod-%s replicas = %d, want %d", pool.name, got, pool.replicas)
+		}
+		if got := nestedString(md, "spec", "template", "spec", "bootstrap", "configRef", "name"); got != "prod-"+pool.name+"-config" {
+			t.Errorf("MachineDeployment prod-%s configRef = %q, want prod-%s-config", pool.name, got, pool.name)
+		}
+		if got := nestedString(md, "spec", "template", "spec", "infrastructureRef", "name"); got != "prod-"+pool.name+"-template" {
+			t.Errorf("MachineDeployment prod-%s infrastructureRef = %q, want prod-%s-template", pool.name, got, pool.name)
+		}
+		if got := md.GetLabels()[clusterNameLabel]; got != "prod" {
+			t.Errorf("MachineDeployment prod-%s cluster label = %q, want prod", pool.name, got)
+		}
+		tmpl := byName["ProxmoxMachineTemplate/prod-"+pool.name+"-template"]
+		if got := nestedInt(tmpl, "spec", "template", "spec", "numCores"); got != pool.cores {
+			t.Errorf("ProxmoxMachineTemplate prod-%s-template numCores = %d, want %d", pool.name, got, pool.cores)
+		}
+	}
+}
+
+func TestGenerateInvalidSpec(t *testing.T) {
+	spec := testSpec()
+	spec.ControlPlane.Replicas = 2
+	if _, err := Generate(configx.ClusterSettings{Name: "prod", Namespace: "default"}, spec); err == nil {
+		t.Fatal("Generate() with an even control plane error = nil, want an error")
+	}
+}
+
+func TestMachineNamePattern(t *testing.T) {
+	pattern := MachineNamePattern("prod", []string{"workers", "gpu"})
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"prod-control-plane-x7k2p", true},
+		{"prod-workers-5d8f9c7b4-q2w9r", true},
+		{"prod-gpu-6c9d8-abcde", true},
+		{"prod-eu-control-plane-x7k2p", false},
+		{"prod-eu-workers-5d8f9c7b4-q2w9r", false},
+		{"prod-storage-5d8f9c7b4-q2w9r", false},
+		{"prod-control-plane", false},
+		{"prod-workers", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pattern.MatchString(tt.name); got != tt.want {
+				t.Errorf("MachineNamePattern().MatchString(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package capix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/version"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// UpgradeFieldManager owns the version fields patched by UpgradeKubernetes, so that a later deploy
+// of manifests with the old version reports a conflict instead of silently downgrading.
+const UpgradeFieldManager = "cctl-upgrade"
+
+// maxKubeletSkew is the number of minor versions kubelets may lag behind the API server.
+const maxKubeletSkew = 3
+
+// ErrRemediation is returned when a MachineHealthCheck starts remediating a machine during an upgrade.
+var ErrRemediation = errors.New("machine remediation in progress")
+
+// UpgradeKubernetes rolls the cluster to a new Kubernetes version: the TalosControlPlane is patched
+// first and its rollout awaited, then every MachineDeployment in turn. opts.Timeout applies to each
+// rollout. The upgrade stops with ErrRemediation as soon as a machine is being remediated.
+func UpgradeKubernetes(ctx context.Context, opts WaitOptions, to string) error {
+	c, err := newKubeClient(opts.Kubeconfig)
+	if err != nil {
+		return err
+	}
+	return upgradeKubernetes(ctx, c, opts, to)
+}
+
+func upgradeKubernetes(ctx context.Context, c ctrlclient.Client, opts WaitOptions, to string) error {
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultWaitTimeout
+	}
+	if opts.Interval == 0 {
+		opts.Interval = defaultWaitInterval
+	}
+	target, err := version.ParseSemantic(to)
+	if err != nil {
+		return fmt.Errorf("invalid kubernetes version %q: %w", to, err)
+	}
+	to = "v" + target.String()
+
+	name := opts.Cluster
+	cp, mds, err := clusterOwners(ctx, c, opts.Namespace, name)
+	if err != nil {
+		return err
+	}
+	if err := checkSkew(cp, mds, target); err != nil {
+		return err
+	}
+	if err := checkRemediation(ctx, c, opts.Namespace, name); err != nil {
+		return err
+	}
+
+	w := &clusterWatch{opts: opts, phases: map[string]string{}, ready: map[string]bool{}}
+	r := rollout{c: c, w: w, what: "Kubernetes version", to: to}
+	if err := r.run(ctx, cp, []string{"spec", "version"},
+		ctrlclient.MatchingLabels{clusterNameLabel: name, controlPlaneLabel: ""}); err != nil {
+		return err
+	}
+	for i := range mds {
+		md := &mds[i]
+		if err := r.run(ctx, md, []string{"spec", "template", "spec", "version"},
+			ctrlclient.MatchingLabels{clusterNameLabel: name, deploymentNameLabel: md.GetName()}); err != nil {
+			return err
+		}
+	}
+	log.Info().Str("cluster", name).Str("version", to).Msg("Kubernetes upgrade complete")
+	return nil
+}
+
+// clusterOwners returns the TalosControlPlane and the MachineDeployments of the cluster.
+func clusterOwners(ctx context.Context, c ctrlclient.Client, namespace, name string) (*unstructured.Unstructured, []unstructured.Unstructured, error) {
+	cluster := newObject(ClusterGVK)
+	if err := c.Get(ctx, ctrlclient.ObjectKey{Namespace: namespace, Name: name}, cluster); err != nil {
+		return nil, nil, fmt.Errorf("get cluster %s: %w", name, err)
+	}
+	cp := newObject(TalosControlPlaneGVK)
+	cpName := nestedString(cluster, "spec", "controlPlaneRef", "name")
+	if err := c.Get(ctx, ctrlclient.ObjectKey{Namespace: namespace, Name: cpName}, cp); err != nil {
+		return nil, nil, fmt.Errorf("get control plane %s: %w", cpName, err)
+	}
+	mds := newList(MachineDeploymentGVK)
+	if err := c.List(ctx, mds, ctrlclient.InNamespace(namespace), ctrlclient.MatchingLabels{clusterNameLabel: name}); err != nil {
+		return nil, nil, fmt.Errorf("list machinedeployments: %w", err)
+	}
+	return cp, mds.Items, nil
+}
+
+// checkSkew allows patch upgrades and a single minor step for the control plane, and requires
+// every MachineDeployment to stay within the supported kubelet skew of the new control plane.
+func checkSkew(cp *unstructured.Unstructured, mds []unstructured.Unstructured, target *version.Version) error {
+	current, err := version.ParseSemantic(nestedString(cp, "spec", "version"))
+	if err != nil {
+		return fmt.Errorf("control plane %s: invalid version: %w", cp.GetName(), err)
+	}
+	switch {
+	case target.LessThan(current):
+		return fmt.Errorf("downgrade from v%s to v%s is not supported", current, target)
+	case target.Major() != current.Major() || target.Minor() > current.Minor()+1:
+		return fmt.Errorf("control plane is at v%s; upgrade one minor version at a time (to v%d.%d.x)",
+			current, current.Major(), current.Minor()+1)
+	}
+
+	for i := range mds {
+		md := &mds[i]
+		v, err := version.ParseSemantic(nestedString(md, "spec", "template", "spec", "version"))
+		if err != nil {
+			return fmt.Errorf("machinedeployment %s: invalid version: %w", md.GetName(), err)
+		}
+		if v.GreaterThan(target) {
+			return fmt.Errorf("machinedeployment %s is at v%s, newer than v%s", md.GetName(), v, target)
+		}
+		if v.Minor()+maxKubeletSkew < target.Minor() {
+			return fmt.Errorf("machinedeployment %s is at v%s; kubelets may lag at most %d minor versions behind v%s",
+				md.GetName(), v, maxKubeletSkew, target)
+		}
+	}
+	return nil
+}
+
+// checkRemediation fails with ErrRemediation if a machine of the cluster is being remediated.
+func checkRemediation(ctx context.Context, c ctrlclient.Client, namespace, cluster string) error {
+	machines := newList(MachineGVK)
+	if err := c.List(ctx, machines, ctrlclient.InNamespace(namespace), ctrlclient.MatchingLabels{clusterNameLabel: cluster}); err != nil {
+		return fmt.Errorf("list machines: %w", err)
+	}
+	var remediating []string
+	for i := range machines.Items {
+		m := &machines.Items[i]
+		if cond := findCondition(conditions(m), "OwnerRemediated"); cond != nil && cond.Status == "False" {
+			remediating = append(remediating, m.GetName()+": "+cond.String())
+		}
+	}
+	if len(remediating) > 0 {
+		return fmt.Errorf("%w; aborting upgrade:\n  %s", ErrRemediation, strings.Join(remediating, "\n  "))
+	}
+	return nil
+}
+
+// rollout patches a field of one owner (control plane or MachineDeployment) and waits until
+// all of its machines have been replaced with the new value.
+type rollout struct {
+	c    ctrlclient.Client
+	w    *clusterWatch
+	what string // What is rolled out, for logs (e.g. "Kubernetes version")
+	to   string
+
+	// updated reports whether a machine runs the new value; nil compares spec.version with to.
+	updated func(ctx context.Context, m *unstructured.Unstructured) (bool, error)
+}
+
+func (r rollout) run(ctx context.Context, owner *unstructured.Unstructured, field []string, machines ctrlclient.MatchingLabels) error {
+	ref := owner.GetKind() + "/" + owner.GetName()
+	if nestedString(owner, field...) != r.to {
+		if err := r.patch(ctx, owner, field); err != nil {
+			return err
+		}
+		log.Info().Str("object", ref).Str("to", r.to).Msg("Rolling out " + r.what)
+	} else {
+		log.Info().Str("object", ref).Str("to", r.to).Msg("Already set; waiting for rollout")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.w.opts.Timeout)
+	defer cancel()
+	ticker := time.NewTicker(r.w.opts.Interval)
+	defer ticker.Stop()
+
+	var last string
+	for {
+		progress, done, err := r.poll(ctx, owner, machines)
+		switch {
+		case errors.Is(err, ErrRemediation):
+			return fmt.Errorf("%s rollout stopped: %w", ref, err)
+		case err != nil:
+			if ctx.Err() == nil {
+				log.Warn().Err(err).Msg("Failed to read rollout status; retrying")
+			}
+		case done:
+			log.Info().Str("object", ref).Str("to", r.to).Msg("Rollout complete")
+			return nil
+		case progress != last:
+			log.Info().Str("object", ref).Str("progress", progress).Msg("Rollout progress")
+			last = progress
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return ctx.Err()
+			}
+			return fmt.Errorf("timed out after %s waiting for %s to roll out %s (%s)", r.w.opts.Timeout, ref, r.to, last)
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r rollout) patch(ctx context.Context, owner *unstructured.Unstructured, field []string) error {
+	patch := map[string]any{}
+	if err := unstructured.SetNestedField(patch, r.to, field...); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+	if err := r.c.Patch(ctx, owner, ctrlclient.RawPatch(types.MergePatchType, raw), ctrlclient.FieldOwner(UpgradeFieldManager)); err != nil {
+		return fmt.Errorf("patch %s/%s %s: %w", owner.GetKind(), owner.GetName(), strings.Join(field, "."), err)
+	}
+	return nil
+}
+
+// poll reports the updated and ready machines of the owner of the owner and whether the rollout is done.
+func (r rollout) poll(ctx context.Context, owner *unstructured.Unstructured, selector ctrlclient.MatchingLabels) (string, bool, error) {
+	cur := newObject(owner.GroupVersionKind())
+	if err := r.c.Get(ctx, ctrlclient.ObjectKeyFromObject(owner), cur); err != nil {
+		return "", false, err
+	}
+	machines := newList(MachineGVK)
+	if err := r.c.List(ctx, machines, ctrlclient.InNamespace(owner.GetNamespace()), selector); err != nil {
+		return "", false, err
+	}
+	if err := checkRemediation(ctx, r.c, owner.GetNamespace(), r.w.opts.Cluster); err != nil {
+		return "", false, err
+	}
+
+	desired := nestedInt(cur, "spec", "replicas")
+	var total, updated, ready int64
+	for i := range machines.Items {
+		m := &machines.Items[i]
+		phase := nestedString(m, "status", "phase")
+		r.w.phaseChanged(m, phase)
+		if m.GetDeletionTimestamp() != nil {
+			continue
+		}
+		total++
+		if r.updated == nil {
+			if nestedString(m, "spec", "version") != r.to {
+				continue
+			}
+		} else if ok, err := r.updated(ctx, m); err != nil {
+			return "", false, err
+		} else if !ok {
+			continue
+		}
+		updated++
+		if phase == "Running" && nestedString(m, "status", "nodeRef", "name") != "" {
+			ready++
+		}
+	}
+
+	progress := fmt.Sprintf("%d/%d updated, %d/%d ready", updated, desired, ready, desired)
+	done := total == desired && updated == desired && ready == desired && conditionOK(cur, "Ready")
+	if observed, found, _ := unstructured.NestedInt64(cur.Object, "status", "observedGeneration"); found && observed < cur.GetGeneration() {
+		done = false // the controller has not seen the new version yet
+	}
+	return progress, done, nil
+}
@@ -0,0 +1,54 @@
+package capix
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+func TestCheckSkew(t *testing.T) {
+	controlPlane := func(v string) *unstructured.Unstructured {
+		cp := newObject(TalosControlPlaneGVK)
+		cp.SetName("coffee-cluster-cp")
+		_ = unstructured.SetNestedField(cp.Object, v, "spec", "version")
+		return cp
+	}
+	deployment := func(v string) unstructured.Unstructured {
+		md := newObject(MachineDeploymentGVK)
+		md.SetName("coffee-cluster-workers")
+		_ = unstructured.SetNestedField(md.Object, v, "spec", "template", "spec", "version")
+		return *md
+	}
+	tests := []struct {
+		name    string
+		cp      string
+		mds     []string
+		target  string
+		wantErr bool
+	}{
+		{name: "patch", cp: "v1.33.1", mds: []string{"v1.33.1"}, target: "1.33.4"},
+		{name: "one minor", cp: "v1.33.4", mds: []string{"v1.33.4"}, target: "1.34.1"},
+		{name: "same version", cp: "v1.34.1", mds: []string{"v1.34.1"}, target: "1.34.1"},
+		{name: "two minors", cp: "v1.32.0", target: "1.34.0", wantErr: true},
+		{name: "downgrade", cp: "v1.34.1", target: "1.34.0", wantErr: true},
+		{name: "major", cp: "v1.34.1", target: "2.0.0", wantErr: true},
+		{name: "workers at max skew", cp: "v1.33.0", mds: []string{"v1.31.5"}, target: "1.34.0"},
+		{name: "workers beyond skew", cp: "v1.33.0", mds: []string{"v1.30.9"}, target: "1.34.0", wantErr: true},
+		{name: "workers newer than target", cp: "v1.33.0", mds: []string{"v1.34.2"}, target: "1.34.0", wantErr: true},
+		{name: "invalid control plane version", cp: "latest", target: "1.34.0", wantErr: true},
+		{name: "invalid worker version", cp: "v1.33.0", mds: []string{""}, target: "1.34.0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mds := make([]unstructured.Unstructured, 0, len(tt.mds))
+			for _, v := range tt.mds {
+				mds = append(mds, deployment(v))
+			}
+			err := checkSkew(controlPlane(tt.cp), mds, version.MustParseSemantic(tt.target))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkSkew() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package capix
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Kinds of the Cluster API objects cctl inspects in the management cluster.
+var (
+	ClusterGVK                = schema.FromAPIVersionAndKind(ClusterAPIVersion, "Cluster")
+	MachineGVK                = schema.FromAPIVersionAndKind(ClusterAPIVersion, "Machine")
+	MachineDeploymentGVK      = schema.FromAPIVersionAndKind(ClusterAPIVersion, "MachineDeployment")
+	MachineHealthCheckGVK     = schema.FromAPIVersionAndKind(ClusterAPIVersion, "MachineHealthCheck")
+	TalosControlPlaneGVK      = schema.FromAPIVersionAndKind(ControlPlaneAPIVersion, "TalosControlPlane")
+	ProxmoxClusterGVK         = schema.FromAPIVersionAndKind(InfrastructureAPIVersion, "ProxmoxCluster")
+	ProxmoxMachineGVK         = schema.FromAPIVersionAndKind(InfrastructureAPIVersion, "ProxmoxMachine")
+	ProxmoxMachineTemplateGVK = schema.FromAPIVersionAndKind(InfrastructureAPIVersion, "ProxmoxMachineTemplate")
+)
+
+// restConfig loads the kubeconfig at path, or the default loading rules ($KUBECONFIG, ~/.kube/config)
+// when path is empty.
+func restConfig(kubeconfig string) (*rest.Config, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	cfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("load kubeconfig: %w", err)
+	}
+	return cfg, nil
+}
+
+// newKubeClient returns a controller-runtime client for unstructured objects.
+func newKubeClient(kubeconfig string) (ctrlclient.Client, error) {
+	cfg, err := restConfig(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	c, err := ctrlclient.New(cfg, ctrlclient.Options{})
+	if err != nil {
+		return nil, fmt.Errorf("create kubernetes client: %w", err)
+	}
+	return c, nil
+}
+
+func newObject(gvk schema.GroupVersionKind) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	return obj
+}
+
+func newList(gvk schema.GroupVersionKind) *unstructured.UnstructuredList {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	return list
+}
+
+// Condition is a Cluster API (v1beta1) status condition.
+type Condition struct {
+	Type     string `json:"type"`
+	Status   string `json:"status"`
+	Severity string `json:"severity,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	Message  string `json:"message,omitempty"`
+}
+
+// String renders the condition as Type=Status (Reason, severity): Message.
+func (c Condition) String() string {
+	s := c.Type + "=" + c.Status
+	switch {
+	case c.Reason != "" && c.Severity != "":
+		s += fmt.Sprintf(" (%s, %s)", c.Reason, c.Severity)
+	case c.Reason != "":
+		s += fmt.Sprintf(" (%s)", c.Reason)
+	}
+	if c.Message != "" {
+		s += ": " + c.Message
+	}
+	return s
+}
+
+func conditions(obj *unstructured.Unstructured) []Condition {
+	raw, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	out := make([]Condition, 0, len(raw))
+	for _, item := range raw {
+		m, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		str := func(key string) string { s, _ := m[key].(string); return s }
+		out = append(out, Condition{
+			Type:     str("type"),
+			Status:   str("status"),
+			Severity: str("severity"),
+			Reason:   str("reason"),
+			Message:  str("message"),
+		})
+	}
+	return out
+}
+
+func findCondition(conds []Condition, condType string) *Condition {
+	for i := range conds {
+		if conds[i].Type == condType {
+			return &conds[i]
+		}
+	}
+	return nil
+}
+
+func isTrue(obj *unstructured.Unstructured, condType string) bool {
+	c := findCondition(conditions(obj), condType)
+	return c != nil && c.Status == "True"
+}
+
+func nestedInt(obj *unstructured.Unstructured, fields ...string) int64 {
+	v, _, _ := unstructured.NestedInt64(obj.Object, fields...)
+	return v
+}
+
+func nestedString(obj *unstructured.Unstructured, fields ...string) string {
+	v, _, _ := unstructured.NestedString(obj.Object, fields...)
+	return v
+}
+
+// ProxmoxMachine is the subset of a CAPMOX ProxmoxMachine needed to correlate it with Proxmox VMs.
+type ProxmoxMachine struct {
+	Namespace string
+	Name      string
+	Cluster   string
+	VMID      int64 // 0 until the provider has created the VM
+}
+
+// ListProxmoxMachines returns the ProxmoxMachines in namespace, or in all namespaces when it is empty.
+func ListProxmoxMachines(ctx context.Context, kubeconfig, namespace string) ([]ProxmoxMachine, error) {
+	c, err := newKubeClient(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	return listProxmoxMachines(ctx, c, namespace)
+}
+
+func listProxmoxMachines(ctx context.Context, c ctrlclient.Client, namespace string) ([]ProxmoxMachine, error) {
+	list := newList(ProxmoxMachineGVK)
+	if err := c.List(ctx, list, ctrlclient.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("list proxmoxmachines: %w", err)
+	}
+	machines := make([]ProxmoxMachine, 0, len(list.Items))
+	for i := range list.Items {
+		item := &list.Items[i]
+		machines = append(machines, ProxmoxMachine{
+			Namespace: item.GetNamespace(),
+			Name:      item.GetName(),
+			Cluster:   item.GetLabels()[clusterNameLabel],
+			VMID:      nestedInt(item, "spec", "virtualMachineID"),
+		})
+	}
+	return machines, nil
+}
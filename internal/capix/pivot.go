@@ -0,0 +1,201 @@
+package capix
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PivotOptions configures Pivot.
+type PivotOptions struct {
+	From      string // Kubeconfig of the current management cluster
+	To        string // Kubeconfig of the cluster that takes over
+	Namespace string // Namespace of the Cluster API objects
+	Cluster   string // Cluster name
+
+	// Init configures the provider installation in To. The providers installed in From are added
+	// with their versions, so only the clusterctl config, variables and wait timeout are used.
+	Init InitOptions
+}
+
+// pivotKinds are counted in both clusters to verify the move.
+var pivotKinds = []schema.GroupVersionKind{
+	ClusterGVK,
+	ProxmoxClusterGVK,
+	TalosControlPlaneGVK,
+	MachineDeploymentGVK,
+	MachineGVK,
+	ProxmoxMachineGVK,
+}
+
+// Pivot moves the Cluster API objects of the cluster from one management cluster to another:
+// the providers installed in From are installed in To (same versions) and waited for, clusterctl
+// move transfers the objects, and the object counts in To are compared with those seen in From.
+func Pivot(ctx context.Context, opts PivotOptions) error {
+	// clusterctl move needs explicit kubeconfig paths on both sides.
+	if opts.From == "" {
+		opts.From = defaultKubeconfig()
+	}
+	if opts.To == "" {
+		opts.To = defaultKubeconfig()
+	}
+	if opts.From == opts.To {
+		return fmt.Errorf("source and target kubeconfig are the same (%s)", opts.From)
+	}
+
+	from, err := newKubeClient(opts.From)
+	if err != nil {
+		return err
+	}
+	to, err := newKubeClient(opts.To)
+	if err != nil {
+		return err
+	}
+
+	cluster := newObject(ClusterGVK)
+	if err := from.Get(ctx, ctrlclient.ObjectKey{Namespace: opts.Namespace, Name: opts.Cluster}, cluster); err != nil {
+		return fmt.Errorf("get cluster %s in source management cluster: %w", opts.Cluster, err)
+	}
+	want, err := countObjects(ctx, from, opts.Namespace, opts.Cluster)
+	if err != nil {
+		return err
+	}
+
+	if err := ensureProviders(ctx, from, to, opts); err != nil {
+		return err
+	}
+
+	c, err := newClient(ctx, opts.Init.ClusterctlConfig, opts.Init.Variables)
+	if err != nil {
+		return err
+	}
+	log.Info().Str("cluster", opts.Cluster).Str("namespace", opts.Namespace).Msg("Moving Cluster API objects")
+	if err := c.Move(ctx, client.MoveOptions{
+		FromKubeconfig: client.Kubeconfig{Path: opts.From},
+		ToKubeconfig:   client.Kubeconfig{Path: opts.To},
+		Namespace:      opts.Namespace,
+	}); err != nil {
+		return fmt.Errorf("move cluster %s: %w", opts.Cluster, err)
+	}
+
+	got, err := countObjects(ctx, to, opts.Namespace, opts.Cluster)
+	if err != nil {
+		return err
+	}
+	var missing []string
+	for _, gvk := range pivotKinds {
+		if got[gvk.Kind] < want[gvk.Kind] {
+			missing = append(missing, fmt.Sprintf("%s %d/%d", gvk.Kind, got[gvk.Kind], want[gvk.Kind]))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("move of cluster %s incomplete; objects in target: %s", opts.Cluster, strings.Join(missing, ", "))
+	}
+	log.Info().Str("cluster", opts.Cluster).Interface("objects", got).Msg("Cluster API objects moved")
+	return nil
+}
+
+// ensureProviders installs the providers of the source management cluster that the target lacks.
+func ensureProviders(ctx context.Context, from, to ctrlclient.Client, opts PivotOptions) error {
+	source, err := listProviders(ctx, from)
+	if err != nil {
+		return err
+	}
+	if len(source) == 0 {
+		return fmt.Errorf("no Cluster API providers found in source management cluster")
+	}
+	target, err := listProviders(ctx, to)
+	if err != nil {
+		return err
+	}
+	installed := make(map[string]bool, len(target))
+	for _, p := range target {
+		installed[p.Type+"/"+p.Name] = true
+	}
+
+	initOpts := opts.Init
+	initOpts.Kubeconfig = opts.To
+	var missing []string
+	for _, p := range source {
+		if installed[p.Type+"/"+p.Name] {
+			continue
+		}
+		missing = append(missing, p.String())
+		switch p.Type {
+		case "CoreProvider":
+			initOpts.Core = p.String()
+		case "BootstrapProvider":
+			initOpts.Bootstrap = append(initOpts.Bootstrap, p.String())
+		case "ControlPlaneProvider":
+			initOpts.ControlPlane = append(initOpts.ControlPlane, p.String())
+		case "InfrastructureProvider":
+			initOpts.Infrastructure = append(initOpts.Infrastructure, p.String())
+		case "IPAMProvider":
+			initOpts.IPAM = append(initOpts.IPAM, p.String())
+		case "AddonProvider":
+			initOpts.Addon = append(initOpts.Addon, p.String())
+		default:
+			log.Warn().Str("provider", p.String()).Str("type", p.Type).Msg("Provider type not supported by pivot; install it manually")
+		}
+	}
+	if len(missing) == 0 {
+		log.Info().Msg("All providers already installed in target management cluster")
+		return nil
+	}
+
+	log.Info().Strs("providers", missing).Msg("Installing providers in target management cluster")
+	if err := Init(ctx, initOpts); err != nil {
+		return fmt.Errorf("install providers in target management cluster: %w", err)
+	}
+	return nil
+}
+
+// defaultKubeconfig returns the first $KUBECONFIG entry, or ~/.kube/config.
+func defaultKubeconfig() string {
+	if paths := filepath.SplitList(os.Getenv(clientcmd.RecommendedConfigPathEnvVar)); len(paths) > 0 && paths[0] != "" {
+		return paths[0]
+	}
+	return clientcmd.RecommendedHomeFile
+}
+
+func countObjects(ctx context.Context, c ctrlclient.Client, namespace, cluster string) (map[string]int, error) {
+	counts := make(map[string]int, len(pivotKinds))
+	for _, gvk := range pivotKinds {
+		if gvk == ClusterGVK {
+			obj := newObject(gvk)
+			err := c.Get(ctx, ctrlclient.ObjectKey{Namespace: namespace, Name: cluster}, obj)
+			switch {
+			case err == nil:
+				counts[gvk.Kind] = 1
+			case !apierrors.IsNotFound(err) && !meta.IsNoMatchError(err):
+				return nil, fmt.Errorf("get cluster %s: %w", cluster, err)
+			}
+			continue
+		}
+		list := newList(gvk)
+		listOpts := []ctrlclient.ListOption{ctrlclient.InNamespace(namespace)}
+		if gvk != ProxmoxClusterGVK && gvk != TalosControlPlaneGVK {
+			// Infrastructure clusters and control planes are not reliably labeled; count the namespace,
+			// which clusterctl move transfers as a whole.
+			listOpts = append(listOpts, ctrlclient.MatchingLabels{clusterNameLabel: cluster})
+		}
+		if err := c.List(ctx, list, listOpts...); err != nil {
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return nil, fmt.Errorf("list %s: %w", strings.ToLower(gvk.Kind)+"s", err)
+		}
+		counts[gvk.Kind] = len(list.Items)
+	}
+	return counts, nil
+}
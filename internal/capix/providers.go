@@ -0,0 +1,240 @@
+package capix
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ProviderGVK is the clusterctl inventory record of an installed provider.
+var ProviderGVK = schema.FromAPIVersionAndKind("clusterctl.cluster.x-k8s.io/v1alpha3", "Provider")
+
+// Provider is an installed Cluster API provider.
+type Provider struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"` // e.g. CoreProvider, InfrastructureProvider
+	Version   string `json:"version"`
+	Namespace string `json:"namespace"`
+}
+
+// String renders the provider as clusterctl expects it in init ("name:version").
+func (p Provider) String() string {
+	if p.Version == "" {
+		return p.Name
+	}
+	return p.Name + ":" + p.Version
+}
+
+// ManifestLabel returns the clusterctl label of the provider, e.g. bootstrap-talos.
+func (p Provider) ManifestLabel() string {
+	return clusterctlv1.ManifestLabel(p.Name, clusterctlv1.ProviderType(p.Type))
+}
+
+// PinnedVersion returns the version pinned for a provider in versions (see configx.ProviderVersions):
+// the manifest label (bootstrap-talos) takes precedence over the plain name (talos).
+func PinnedVersion(versions map[string]string, providerType, name string) string {
+	name = strings.ToLower(name)
+	if v, ok := versions[clusterctlv1.ManifestLabel(name, clusterctlv1.ProviderType(providerType))]; ok {
+		return v
+	}
+	return versions[name]
+}
+
+// pinProviders adds the pinned version to providers given without one.
+func pinProviders(versions map[string]string, providerType string, providers []string) []string {
+	out := make([]string, 0, len(providers))
+	for _, p := range providers {
+		if p != "" && !strings.Contains(p, ":") {
+			if v := PinnedVersion(versions, providerType, p); v != "" {
+				p += ":" + v
+			}
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// ListProviders returns the providers installed in the management cluster.
+func ListProviders(ctx context.Context, kubeconfig string) ([]Provider, error) {
+	c, err := newKubeClient(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	return listProviders(ctx, c)
+}
+
+// listProviders reads the clusterctl inventory; a cluster without it has no providers.
+func listProviders(ctx context.Context, c ctrlclient.Client) ([]Provider, error) {
+	list := newList(ProviderGVK)
+	if err := c.List(ctx, list); err != nil {
+		if meta.IsNoMatchError(err) || apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("list providers: %w", err)
+	}
+	providers := make([]Provider, 0, len(list.Items))
+	for i := range list.Items {
+		item := &list.Items[i]
+		providers = append(providers, Provider{
+			Name:      nestedString(item, "providerName"),
+			Type:      nestedString(item, "type"),
+			Version:   nestedString(item, "version"),
+			Namespace: item.GetNamespace(),
+		})
+	}
+	sort.Slice(providers, func(i, j int) bool {
+		if providers[i].Type != providers[j].Type {
+			return providers[i].Type < providers[j].Type
+		}
+		return providers[i].Name < providers[j].Name
+	})
+	return providers, nil
+}
+
+// UpgradeOptions configures PlanUpgrade, ProviderStatuses and ApplyUpgrade.
+type UpgradeOptions struct {
+	ClusterctlConfig string            // Path to clusterctl.yaml (empty for the clusterctl default)
+	Kubeconfig       string            // Management cluster kubeconfig (empty for the default loading rules)
+	Versions         map[string]string // Pinned provider versions (see configx.ProviderVersions)
+	Variables        map[string]string // Override clusterctl's variable resolution
+	Contract         string            // Upgrade every provider to the latest release of this contract
+	WaitTimeout      time.Duration     // Wait up to this long per provider for its controllers (0 does not wait)
+}
+
+// UpgradeItem is a provider in an upgrade plan.
+type UpgradeItem struct {
+	Provider
+	Next   string `json:"next,omitempty"`   // Latest release for the contract; empty when up to date
+	Pinned string `json:"pinned,omitempty"` // Version pinned in the cctl config
+}
+
+// UpgradePlan lists the provider upgrades available for a Cluster API contract.
+type UpgradePlan struct {
+	Contract  string        `json:"contract"`
+	Providers []UpgradeItem `json:"providers"`
+}
+
+// PlanUpgrade returns the upgrade plans computed by clusterctl, one per contract, with the
+// pinned versions of the providers.
+func PlanUpgrade(ctx context.Context, opts UpgradeOptions) ([]UpgradePlan, error) {
+	c, err := newClient(ctx, opts.ClusterctlConfig, opts.Variables)
+	if err != nil {
+		return nil, err
+	}
+	plans, err := c.PlanUpgrade(ctx, client.PlanUpgradeOptions{Kubeconfig: client.Kubeconfig{Path: opts.Kubeconfig}})
+	if err != nil {
+		return nil, fmt.Errorf("plan upgrade: %w", err)
+	}
+
+	out := make([]UpgradePlan, 0, len(plans))
+	for _, plan := range plans {
+		p := UpgradePlan{Contract: plan.Contract}
+		for _, item := range plan.Providers {
+			p.Providers = append(p.Providers, UpgradeItem{
+				Provider: Provider{
+					Name:      item.ProviderName,
+					Type:      item.Type,
+					Version:   item.Version,
+					Namespace: item.Namespace,
+				},
+				Next:   item.NextVersion,
+				Pinned: PinnedVersion(opts.Versions, item.Type, item.ProviderName),
+			})
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// ProviderStatuses returns the installed providers with the latest release available for the
+// newest contract in the upgrade plan.
+func ProviderStatuses(ctx context.Context, opts UpgradeOptions) ([]UpgradeItem, error) {
+	plans, err := PlanUpgrade(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(plans) == 0 {
+		return nil, nil
+	}
+	// clusterctl orders the plans by contract, oldest first.
+	items := plans[len(plans)-1].Providers
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Type != items[j].Type {
+			return items[i].Type < items[j].Type
+		}
+		return items[i].Name < items[j].Name
+	})
+	return items, nil
+}
+
+// ApplyUpgrade upgrades the providers of the management cluster. With a contract every provider
+// is upgraded to the latest release of that contract; otherwise the providers whose installed
+// version differs from the pinned one are moved to the pinned version.
+func ApplyUpgrade(ctx context.Context, opts UpgradeOptions) error {
+	applyOpts := client.ApplyUpgradeOptions{
+		Kubeconfig:          client.Kubeconfig{Path: opts.Kubeconfig},
+		Contract:            opts.Contract,
+		WaitProviders:       opts.WaitTimeout > 0,
+		WaitProviderTimeout: opts.WaitTimeout,
+	}
+
+	if opts.Contract != "" {
+		if len(opts.Versions) > 0 {
+			log.Warn().Str("contract", opts.Contract).Msg("Upgrading to the latest releases of the contract; pinned versions are ignored")
+		}
+	} else {
+		installed, err := ListProviders(ctx, opts.Kubeconfig)
+		if err != nil {
+			return err
+		}
+		var changes []string
+		for _, p := range installed {
+			pinned := PinnedVersion(opts.Versions, p.Type, p.Name)
+			if pinned == "" || pinned == p.Version {
+				continue
+			}
+			ref := p.Name + ":" + pinned
+			switch clusterctlv1.ProviderType(p.Type) {
+			case clusterctlv1.CoreProviderType:
+				applyOpts.CoreProvider = ref
+			case clusterctlv1.BootstrapProviderType:
+				applyOpts.BootstrapProviders = append(applyOpts.BootstrapProviders, ref)
+			case clusterctlv1.ControlPlaneProviderType:
+				applyOpts.ControlPlaneProviders = append(applyOpts.ControlPlaneProviders, ref)
+			case clusterctlv1.InfrastructureProviderType:
+				applyOpts.InfrastructureProviders = append(applyOpts.InfrastructureProviders, ref)
+			case clusterctlv1.IPAMProviderType:
+				applyOpts.IPAMProviders = append(applyOpts.IPAMProviders, ref)
+			case clusterctlv1.RuntimeExtensionProviderType:
+				applyOpts.RuntimeExtensionProviders = append(applyOpts.RuntimeExtensionProviders, ref)
+			case clusterctlv1.AddonProviderType:
+				applyOpts.AddonProviders = append(applyOpts.AddonProviders, ref)
+			}
+			changes = append(changes, fmt.Sprintf("%s %s -> %s", p.ManifestLabel(), p.Version, pinned))
+		}
+		if len(changes) == 0 {
+			log.Info().Msg("Installed providers match the pinned versions; nothing to upgrade")
+			return nil
+		}
+		log.Info().Strs("upgrades", changes).Msg("Upgrading providers to pinned versions")
+	}
+
+	c, err := newClient(ctx, opts.ClusterctlConfig, opts.Variables)
+	if err != nil {
+		return err
+	}
+	if err := c.ApplyUpgrade(ctx, applyOpts); err != nil {
+		return fmt.Errorf("apply upgrade: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package capix
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPinnedVersion(t *testing.T) {
+	versions := map[string]string{
+		"talos":               "v0.6.7",
+		"control-plane-talos": "v0.5.8",
+		"proxmox":             "v0.7.4",
+	}
+	tests := []struct {
+		providerType, name string
+		want               string
+	}{
+		{"BootstrapProvider", "talos", "v0.6.7"},
+		{"ControlPlaneProvider", "talos", "v0.5.8"},
+		{"ControlPlaneProvider", "Talos", "v0.5.8"},
+		{"InfrastructureProvider", "proxmox", "v0.7.4"},
+		{"CoreProvider", "cluster-api", ""},
+	}
+	for _, tt := range tests {
+		if got := PinnedVersion(versions, tt.providerType, tt.name); got != tt.want {
+			t.Errorf("PinnedVersion(%s, %s) = %q, want %q", tt.providerType, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPinProviders(t *testing.T) {
+	versions := map[string]string{"bootstrap-talos": "v0.6.7", "kubeadm": "v1.11.2"}
+	got := pinProviders(versions, "BootstrapProvider", []string{"talos", "talos:v0.6.5", "kubeadm", "other", ""})
+	want := []string{"talos:v0.6.7", "talos:v0.6.5", "kubeadm:v1.11.2", "other", ""}
+	if !slices.Equal(got, want) {
+		t.Fatalf("pinProviders() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,261 @@
+package capix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ActionPruned is reported for objects deleted because they are no longer in the manifests.
+const ActionPruned = "pruned"
+
+// InventoryLabel marks the ConfigMap that records the objects applied by cctl for an inventory.
+const InventoryLabel = "cctl.zerodi.ru/inventory"
+
+const (
+	inventoryKey        = "objects"
+	defaultPruneTimeout = 10 * time.Minute
+	pruneInterval       = 2 * time.Second
+)
+
+var configMapGVK = schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+
+// ObjectRef identifies an object recorded in the inventory.
+type ObjectRef struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+func refOf(obj *unstructured.Unstructured) ObjectRef {
+	return ObjectRef{APIVersion: obj.GetAPIVersion(), Kind: obj.GetKind(), Namespace: obj.GetNamespace(), Name: obj.GetName()}
+}
+
+func (r ObjectRef) object() *unstructured.Unstructured {
+	obj := newObject(schema.FromAPIVersionAndKind(r.APIVersion, r.Kind))
+	obj.SetNamespace(r.Namespace)
+	obj.SetName(r.Name)
+	return obj
+}
+
+// key ignores the API version, so an object moved to a newer version is not pruned.
+func (r ObjectRef) key() string {
+	return schema.FromAPIVersionAndKind(r.APIVersion, r.Kind).GroupKind().String() + "/" + r.Namespace + "/" + r.Name
+}
+
+// InventoryName returns the name of the inventory ConfigMap.
+func InventoryName(inventory string) string { return "cctl-inventory-" + inventory }
+
+// inventory is the set of objects recorded in the inventory ConfigMap.
+type inventory struct {
+	namespace string
+	name      string
+	id        string
+}
+
+func newInventory(id, namespace string) inventory {
+	if namespace == "" {
+		namespace = "default"
+	}
+	return inventory{namespace: namespace, name: InventoryName(id), id: id}
+}
+
+// read returns the recorded objects; a missing ConfigMap is an empty inventory.
+func (inv inventory) read(ctx context.Context, c ctrlclient.Client) ([]ObjectRef, error) {
+	cm := newObject(configMapGVK)
+	if err := c.Get(ctx, ctrlclient.ObjectKey{Namespace: inv.namespace, Name: inv.name}, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read inventory %s/%s: %w", inv.namespace, inv.name, err)
+	}
+	raw := nestedString(cm, "data", inventoryKey)
+	if raw == "" {
+		return nil, nil
+	}
+	var refs []ObjectRef
+	if err := json.Unmarshal([]byte(raw), &refs); err != nil {
+		return nil, fmt.Errorf("decode inventory %s/%s: %w", inv.namespace, inv.name, err)
+	}
+	return refs, nil
+}
+
+// write replaces the recorded objects.
+func (inv inventory) write(ctx context.Context, c ctrlclient.Client, refs []ObjectRef) error {
+	sort.Slice(refs, func(i, j int) bool { return refs[i].key() < refs[j].key() })
+	raw, err := json.MarshalIndent(refs, "", "  ")
+	if err != nil {
+		return err
+	}
+	cm := newObject(configMapGVK)
+	cm.SetNamespace(inv.namespace)
+	cm.SetName(inv.name)
+	cm.SetLabels(map[string]string{InventoryLabel: inv.id})
+	if err := unstructured.SetNestedField(cm.Object, map[string]any{inventoryKey: string(raw)}, "data"); err != nil {
+		return err
+	}
+	if err := c.Patch(ctx, cm, ctrlclient.Apply, ctrlclient.FieldOwner(FieldManager), ctrlclient.ForceOwnership); err != nil {
+		return fmt.Errorf("write inventory %s/%s: %w", inv.namespace, inv.name, err)
+	}
+	return nil
+}
+
+// mergeRefs returns the union of a and b, keeping the entry from b for objects in both.
+func mergeRefs(a, b []ObjectRef) []ObjectRef {
+	seen := make(map[string]int, len(a)+len(b))
+	var out []ObjectRef
+	for _, refs := range [][]ObjectRef{a, b} {
+		for _, r := range refs {
+			if i, ok := seen[r.key()]; ok {
+				out[i] = r
+				continue
+			}
+			seen[r.key()] = len(out)
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// staleRefs returns the recorded objects that are not in the applied set.
+func staleRefs(recorded []ObjectRef, objs []*unstructured.Unstructured) []ObjectRef {
+	applied := make(map[string]bool, len(objs))
+	for _, obj := range objs {
+		applied[refOf(obj).key()] = true
+	}
+	var stale []ObjectRef
+	for _, r := range recorded {
+		if !applied[r.key()] {
+			stale = append(stale, r)
+		}
+	}
+	return stale
+}
+
+// pruneRank orders deletions along Cluster API ownership: health checks first so they do not
+// remediate machines being removed, then MachineDeployments and control planes, which own the
+// Machines created from templates, then the Cluster and infrastructure, and the templates last.
+func pruneRank(kind string) int {
+	switch kind {
+	case "MachineHealthCheck":
+		return 0
+	case "MachineDeployment", "MachineSet", "MachinePool":
+		return 1
+	case "TalosControlPlane":
+		return 2
+	case "Cluster":
+		return 3
+	case "ProxmoxCluster":
+		return 4
+	case "ProxmoxMachineTemplate", "TalosConfigTemplate":
+		return 5
+	case "CustomResourceDefinition":
+		return 7
+	case "Namespace":
+		return 8
+	}
+	return 6
+}
+
+// prune deletes the stale objects rank by rank and waits for each rank to be gone before the next
+// one, so owners are removed before the objects they were created from. It returns the objects
+// that were pruned (or would be, in a dry run) and the refs that still exist after an error.
+func prune(ctx context.Context, c ctrlclient.Client, stale []ObjectRef, opts ApplyOptions) ([]ApplyResult, []ObjectRef, error) {
+	sort.SliceStable(stale, func(i, j int) bool { return pruneRank(stale[i].Kind) < pruneRank(stale[j].Kind) })
+
+	var results []ApplyResult
+	for start := 0; start < len(stale); {
+		end := start
+		for end < len(stale) && pruneRank(stale[end].Kind) == pruneRank(stale[start].Kind) {
+			end++
+		}
+		group := stale[start:end]
+
+		for _, r := range group {
+			if err := deleteObject(ctx, c, r, opts.DryRun); err != nil {
+				return results, stale[start:], err
+			}
+			results = append(results, ApplyResult{
+				APIVersion: r.APIVersion,
+				Kind:       r.Kind,
+				Namespace:  r.Namespace,
+				Name:       r.Name,
+				Action:     ActionPruned,
+				DryRun:     opts.DryRun,
+			})
+		}
+		if opts.DryRun == "" {
+			if err := waitDeleted(ctx, c, group, opts.PruneTimeout); err != nil {
+				return results, stale[start:], err
+			}
+		}
+		start = end
+	}
+	return results, nil, nil
+}
+
+func deleteObject(ctx context.Context, c ctrlclient.Client, r ObjectRef, dryRun string) error {
+	delOpts := []ctrlclient.DeleteOption{ctrlclient.PropagationPolicy(metav1.DeletePropagationForeground)}
+	switch dryRun {
+	case DryRunClient:
+		return nil
+	case DryRunServer:
+		delOpts = append(delOpts, ctrlclient.DryRunAll)
+	}
+	obj := r.object()
+	if err := c.Delete(ctx, obj, delOpts...); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("prune %s: %w", describeObject(obj), err)
+	}
+	if dryRun == "" {
+		log.Info().Str("object", describeObject(obj)).Msg("Pruning object")
+	}
+	return nil
+}
+
+func waitDeleted(ctx context.Context, c ctrlclient.Client, refs []ObjectRef, timeout time.Duration) error {
+	if timeout == 0 {
+		timeout = defaultPruneTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		var remaining []string
+		for _, r := range refs {
+			obj := r.object()
+			err := c.Get(ctx, ctrlclient.ObjectKeyFromObject(obj), obj)
+			switch {
+			case apierrors.IsNotFound(err):
+			case err != nil && ctx.Err() == nil:
+				return fmt.Errorf("get %s: %w", describeObject(obj), err)
+			default:
+				remaining = append(remaining, describeObject(obj))
+			}
+		}
+		if len(remaining) == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return ctx.Err()
+			}
+			return fmt.Errorf("timed out after %s waiting for deletion of %v", timeout, remaining)
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package capix
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ScaleFieldManager owns the replica counts patched by Scale, so that a later deploy of manifests
+// with other counts reports a conflict instead of silently scaling back.
+const ScaleFieldManager = "cctl-scale"
+
+// maxPoolAddresses bounds the size of an IPv4 pool expanded by poolAddresses.
+const maxPoolAddresses = 1 << 16
+
+// ScaleRequest sets the replicas of the control plane and of MachineDeployments.
+type ScaleRequest struct {
+	ControlPlane *int64           // Control plane replicas; nil leaves them unchanged
+	Workers      map[string]int64 // Replicas by MachineDeployment name, with or without the <cluster>- prefix
+}
+
+// Scale patches the replicas of the TalosControlPlane and MachineDeployments and waits for the
+// cluster to become ready (see WaitForCluster). The control plane count must be odd for etcd
+// quorum. Before scaling up, the IPv4 pool of the ProxmoxCluster is checked for enough addresses
+// not yet used by a machine of the cluster.
+func Scale(ctx context.Context, opts WaitOptions, req ScaleRequest) error {
+	c, err := newKubeClient(opts.Kubeconfig)
+	if err != nil {
+		return err
+	}
+	return scale(ctx, c, opts, req)
+}
+
+func scale(ctx context.Context, c ctrlclient.Client, opts WaitOptions, req ScaleRequest) error {
+	if req.ControlPlane == nil && len(req.Workers) == 0 {
+		return fmt.Errorf("nothing to scale")
+	}
+	if n := req.ControlPlane; n != nil && (*n < 1 || *n%2 == 0) {
+		return fmt.Errorf("control plane replicas must be odd for etcd quorum, got %d", *n)
+	}
+
+	ns, name := opts.Namespace, opts.Cluster
+	cp, mds, err := clusterOwners(ctx, c, ns, name)
+	if err != nil {
+		return err
+	}
+
+	type change struct {
+		obj      *unstructured.Unstructured
+		from, to int64
+	}
+	var changes []change
+	if req.ControlPlane != nil {
+		changes = append(changes, change{cp, nestedInt(cp, "spec", "replicas"), *req.ControlPlane})
+	}
+	byName := make(map[string]*unstructured.Unstructured, len(mds))
+	for i := range mds {
+		byName[mds[i].GetName()] = &mds[i]
+	}
+	workers := make([]string, 0, len(req.Workers))
+	for w := range req.Workers {
+		workers = append(workers, w)
+	}
+	sort.Strings(workers)
+	for _, w := range workers {
+		n := req.Workers[w]
+		if n < 0 {
+			return fmt.Errorf("machinedeployment %s: replicas must not be negative, got %d", w, n)
+		}
+		md := byName[w]
+		if md == nil {
+			md = byName[name+"-"+w]
+		}
+		if md == nil {
+			names := make([]string, 0, len(byName))
+			for n := range byName {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			return fmt.Errorf("machinedeployment %s not found in cluster %s (available: %s)", w, name, strings.Join(names, ", "))
+		}
+		changes = append(changes, change{md, nestedInt(md, "spec", "replicas"), n})
+	}
+
+	var added int64
+	for _, ch := range changes {
+		if ch.to > ch.from {
+			added += ch.to - ch.from
+		}
+	}
+	if added > 0 {
+		if err := checkFreeAddresses(ctx, c, ns, name, added); err != nil {
+			return err
+		}
+	}
+
+	for _, ch := range changes {
+		ref := ch.obj.GetKind() + "/" + ch.obj.GetName()
+		if ch.from == ch.to {
+			log.Info().Str("object", ref).Int64("replicas", ch.to).Msg("Replicas unchanged")
+			continue
+		}
+		raw, err := json.Marshal(map[string]any{"spec": map[string]any{"replicas": ch.to}})
+		if err != nil {
+			return err
+		}
+		if err := c.Patch(ctx, ch.obj, ctrlclient.RawPatch(types.MergePatchType, raw), ctrlclient.FieldOwner(ScaleFieldManager)); err != nil {
+			return fmt.Errorf("scale %s: %w", ref, err)
+		}
+		log.Info().Str("object", ref).Int64("from", ch.from).Int64("to", ch.to).Msg("Scaling")
+	}
+	return waitForCluster(ctx, c, opts)
+}
+
+// checkFreeAddresses fails unless the IPv4 pool of the cluster's ProxmoxCluster (its
+// infrastructureRef) has at least n addresses that are neither the gateway, the control plane
+// endpoint nor in use by a machine of the cluster. Machines without a pool address yet are counted
+// as they will claim one; machines being deleted are not.
+func checkFreeAddresses(ctx context.Context, c ctrlclient.Client, namespace, cluster string, n int64) error {
+	cl := newObject(ClusterGVK)
+	if err := c.Get(ctx, ctrlclient.ObjectKey{Namespace: namespace, Name: cluster}, cl); err != nil {
+		return fmt.Errorf("get cluster %s: %w", cluster, err)
+	}
+	infra := nestedString(cl, "spec", "infrastructureRef", "name")
+	if kind := nestedString(cl, "spec", "infrastructureRef", "kind"); kind != ProxmoxClusterGVK.Kind || infra == "" {
+		log.Warn().Str("cluster", cluster).Str("infrastructure", kind).Msg("Cluster has no ProxmoxCluster; skipping address check")
+		return nil
+	}
+	pc := newObject(ProxmoxClusterGVK)
+	if err := c.Get(ctx, ctrlclient.ObjectKey{Namespace: namespace, Name: infra}, pc); err != nil {
+		return fmt.Errorf("get proxmoxcluster %s: %w", infra, err)
+	}
+	ranges, _, _ := unstructured.NestedStringSlice(pc.Object, "spec", "ipv4Config", "addresses")
+	if len(ranges) == 0 {
+		log.Warn().Str("proxmoxcluster", infra).Msg("ProxmoxCluster has no IPv4 pool; skipping address check")
+		return nil
+	}
+	pool, err := poolAddresses(ranges)
+	if err != nil {
+		return fmt.Errorf("proxmoxcluster %s: %w", infra, err)
+	}
+	for _, reserved := range []string{
+		nestedString(pc, "spec", "ipv4Config", "gateway"),
+		nestedString(pc, "spec", "controlPlaneEndpoint", "host"),
+	} {
+		if addr, err := netip.ParseAddr(reserved); err == nil {
+			delete(pool, addr)
+		}
+	}
+	size := int64(len(pool))
+
+	machines := newList(MachineGVK)
+	if err := c.List(ctx, machines, ctrlclient.InNamespace(namespace), ctrlclient.MatchingLabels{clusterNameLabel: cluster}); err != nil {
+		return fmt.Errorf("list machines: %w", err)
+	}
+	var used, pending int64
+	for i := range machines.Items {
+		m := &machines.Items[i]
+		if m.GetDeletionTimestamp() != nil {
+			continue
+		}
+		pending++
+		for _, a := range machineAddresses(m) {
+			if addr, err := netip.ParseAddr(a); err == nil && pool[addr] {
+				delete(pool, addr)
+				used++
+				pending--
+				break
+			}
+		}
+	}
+	free := int64(len(pool)) - pending
+	if free < n {
+		return fmt.Errorf("IPv4 pool %s of proxmoxcluster %s has %d free addresses (%d in pool, %d used, %d being provisioned); %d more machines need addresses",
+			strings.Join(ranges, ","), infra, max(free, 0), size, used, pending, n)
+	}
+	log.Info().Int64("free", free).Int64("needed", n).Msg("IPv4 pool has enough free addresses")
+	return nil
+}
+
+// poolAddresses expands the pool entries of a ProxmoxCluster: ranges (192.168.100.210-192.168.100.219),
+// CIDRs (network and broadcast addresses excluded) and single addresses.
+func poolAddresses(entries []string) (map[netip.Addr]bool, error) {
+	pool := map[netip.Addr]bool{}
+	add := func(first, last netip.Addr) error {
+		for a := first; a.Compare(last) <= 0; a = a.Next() {
+			if len(pool) >= maxPoolAddresses {
+				return fmt.Errorf("IPv4 pool larger than %d addresses", maxPoolAddresses)
+			}
+			pool[a] = true
+			if a == last {
+				break
+			}
+		}
+		return nil
+	}
+
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		var (
+			first, last netip.Addr
+			err         error
+		)
+		switch {
+		case strings.Contains(entry, "-"):
+			lo, hi, _ := strings.Cut(entry, "-")
+			if first, err = netip.ParseAddr(strings.TrimSpace(lo)); err == nil {
+				last, err = netip.ParseAddr(strings.TrimSpace(hi))
+			}
+			if err == nil && last.Less(first) {
+				err = fmt.Errorf("range end before start")
+			}
+		case strings.Contains(entry, "/"):
+			var prefix netip.Prefix
+			if prefix, err = netip.ParsePrefix(entry); err == nil && prefix.Addr().Is4() {
+				prefix = prefix.Masked()
+				first = prefix.Addr()
+				last = lastAddr(prefix)
+				if prefix.Bits() < 31 {
+					first, last = first.Next(), last.Prev()
+				}
+			}
+		default:
+			first, err = netip.ParseAddr(entry)
+			last = first
+		}
+		if err == nil && !(first.Is4() && last.Is4()) {
+			err = fmt.Errorf("not an IPv4 address")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid IPv4 pool entry %q: %w", entry, err)
+		}
+		if err := add(first, last); err != nil {
+			return nil, err
+		}
+	}
+	return pool, nil
+}
+
+// lastAddr returns the highest address of an IPv4 prefix.
+func lastAddr(p netip.Prefix) netip.Addr {
+	a := p.Addr().As4()
+	for i := p.Bits(); i < 32; i++ {
+		a[i/8] |= 1 << (7 - i%8)
+	}
+	return netip.AddrFrom4(a)
+}
@@ -0,0 +1,131 @@
+package capix
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPoolAddresses(t *testing.T) {
+	tests := []struct {
+		name        string
+		entries     []string
+		size        int
+		first, last string
+		wantErr     bool
+	}{
+		{name: "range", entries: []string{"192.168.100.210-192.168.100.219"}, size: 10, first: "192.168.100.210", last: "192.168.100.219"},
+		{name: "cidr", entries: []string{"10.0.0.0/29"}, size: 6, first: "10.0.0.1", last: "10.0.0.6"},
+		{name: "unmasked cidr", entries: []string{"10.0.0.5/30"}, size: 2, first: "10.0.0.5", last: "10.0.0.6"},
+		{name: "point-to-point cidr", entries: []string{"10.0.0.4/31"}, size: 2, first: "10.0.0.4", last: "10.0.0.5"},
+		{name: "single address", entries: []string{" 10.0.0.7 "}, size: 1, first: "10.0.0.7", last: "10.0.0.7"},
+		{name: "overlapping entries", entries: []string{"10.0.0.1-10.0.0.4", "10.0.0.3", "10.0.0.0/30"}, size: 4, first: "10.0.0.1", last: "10.0.0.4"},
+		{name: "range end of address space", entries: []string{"255.255.255.254-255.255.255.255"}, size: 2, first: "255.255.255.254", last: "255.255.255.255"},
+		{name: "reversed range", entries: []string{"10.0.0.9-10.0.0.1"}, wantErr: true},
+		{name: "ipv6", entries: []string{"fd00::1"}, wantErr: true},
+		{name: "ipv6 cidr", entries: []string{"fd00::/120"}, wantErr: true},
+		{name: "garbage", entries: []string{"pool"}, wantErr: true},
+		{name: "too large", entries: []string{"10.0.0.0/8"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool, err := poolAddresses(tt.entries)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("poolAddresses() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(pool) != tt.size {
+				t.Fatalf("poolAddresses() has %d addresses, want %d", len(pool), tt.size)
+			}
+			for _, a := range []string{tt.first, tt.last} {
+				if !pool[netip.MustParseAddr(a)] {
+					t.Fatalf("poolAddresses() does not contain %s", a)
+				}
+			}
+		})
+	}
+}
+
+func TestLastAddr(t *testing.T) {
+	tests := []struct {
+		prefix, want string
+	}{
+		{"192.168.100.0/24", "192.168.100.255"},
+		{"10.0.0.0/29", "10.0.0.7"},
+		{"10.0.0.0/31", "10.0.0.1"},
+		{"10.0.0.9/32", "10.0.0.9"},
+		{"172.16.0.0/12", "172.31.255.255"},
+		{"0.0.0.0/0", "255.255.255.255"},
+	}
+	for _, tt := range tests {
+		if got := lastAddr(netip.MustParsePrefix(tt.prefix)); got.String() != tt.want {
+			t.Errorf("lastAddr(%s) = %s, want %s", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestCheckFreeAddresses(t *testing.T) {
+	cluster := newObject(ClusterGVK)
+	cluster.SetNamespace("default")
+	cluster.SetName("coffee")
+	_ = unstructured.SetNestedMap(cluster.Object, map[string]any{
+		"kind": "ProxmoxCluster",
+		"name": "coffee-pve",
+	}, "spec", "infrastructureRef")
+
+	pc := newObject(ProxmoxClusterGVK)
+	pc.SetNamespace("default")
+	pc.SetName("coffee-pve")
+	_ = unstructured.SetNestedStringSlice(pc.Object, []string{"10.0.0.10-10.0.0.15"}, "spec", "ipv4Config", "addresses")
+	_ = unstructured.SetNestedField(pc.Object, "10.0.0.1", "spec", "ipv4Config", "gateway")
+	_ = unstructured.SetNestedField(pc.Object, "10.0.0.10", "spec", "controlPlaneEndpoint", "host")
+
+	machine := func(name, addr string, deleting bool) ctrlclient.Object {
+		m := newObject(MachineGVK)
+		m.SetNamespace("default")
+		m.SetName(name)
+		m.SetLabels(map[string]string{clusterNameLabel: "coffee"})
+		if addr != "" {
+			_ = unstructured.SetNestedSlice(m.Object, []any{
+				map[string]any{"type": "InternalIP", "address": addr},
+			}, "status", "addresses")
+		}
+		if deleting {
+			now := metav1.Now()
+			m.SetDeletionTimestamp(&now)
+			m.SetFinalizers([]string{"machine.cluster.x-k8s.io"})
+		}
+		return m
+	}
+	// 5 usable addresses (10.0.0.11-15): 2 used, 1 claimed by a machine being provisioned.
+	// The machine being deleted is not counted.
+	c := fake.NewClientBuilder().WithObjects(
+		cluster, pc,
+		machine("cp-0", "10.0.0.11", false),
+		machine("cp-1", "10.0.0.12", false),
+		machine("cp-2", "", false),
+		machine("worker-0", "10.0.0.13", true),
+	).Build()
+
+	tests := []struct {
+		n       int64
+		wantErr bool
+	}{
+		{1, false},
+		{2, false},
+		{3, true},
+	}
+	for _, tt := range tests {
+		err := checkFreeAddresses(context.Background(), c, "default", "coffee", tt.n)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkFreeAddresses(%d) error = %v, wantErr %v", tt.n, err, tt.wantErr)
+		}
+	}
+}
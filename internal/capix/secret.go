@@ -0,0 +1,101 @@
+package capix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// restartedAtAnnotation is the pod template annotation `kubectl rollout restart` sets.
+const restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+// ApplySecret creates or updates the Opaque secret namespace/name with data and labels in the
+// cluster of kubeconfig. It reports whether the stored data changed.
+func ApplySecret(ctx context.Context, kubeconfig, namespace, name string, data, labels map[string]string) (bool, error) {
+	c, err := newKubeClient(kubeconfig)
+	if err != nil {
+		return false, err
+	}
+	return applySecret(ctx, c, namespace, name, data, labels)
+}
+
+func applySecret(ctx context.Context, c ctrlclient.Client, namespace, name string, data, labels map[string]string) (bool, error) {
+	if name == "" {
+		return false, errors.New("secret name is required")
+	}
+	raw := make(map[string][]byte, len(data))
+	for k, v := range data {
+		raw[k] = []byte(v)
+	}
+
+	secret := &corev1.Secret{}
+	err := c.Get(ctx, ctrlclient.ObjectKey{Namespace: namespace, Name: name}, secret)
+	switch {
+	case apierrors.IsNotFound(err):
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels},
+			Type:       corev1.SecretTypeOpaque,
+			Data:       raw,
+		}
+		if err := c.Create(ctx, secret); err != nil {
+			return false, fmt.Errorf("create secret %s/%s: %w", namespace, name, err)
+		}
+		return true, nil
+	case err != nil:
+		return false, fmt.Errorf("get secret %s/%s: %w", namespace, name, err)
+	}
+
+	changed := !maps.EqualFunc(secret.Data, raw, func(a, b []byte) bool { return string(a) == string(b) })
+	relabel := false
+	for k, v := range labels {
+		if secret.Labels[k] != v {
+			relabel = true
+		}
+	}
+	if !changed && !relabel {
+		return false, nil
+	}
+	if secret.Labels == nil {
+		secret.Labels = map[string]string{}
+	}
+	maps.Copy(secret.Labels, labels)
+	secret.Data = raw
+	if err := c.Update(ctx, secret); err != nil {
+		return false, fmt.Errorf("update secret %s/%s: %w", namespace, name, err)
+	}
+	return changed, nil
+}
+
+// RestartDeployment triggers a rollout of the Deployment namespace/name like `kubectl rollout restart`:
+// its pod template is annotated with the current time.
+func RestartDeployment(ctx context.Context, kubeconfig, namespace, name string) error {
+	c, err := newKubeClient(kubeconfig)
+	if err != nil {
+		return err
+	}
+	return restartDeployment(ctx, c, namespace, name, time.Now())
+}
+
+func restartDeployment(ctx context.Context, c ctrlclient.Client, namespace, name string, now time.Time) error {
+	raw, err := json.Marshal(map[string]any{"spec": map[string]any{"template": map[string]any{"metadata": map[string]any{
+		"annotations": map[string]string{restartedAtAnnotation: now.Format(time.RFC3339)},
+	}}}})
+	if err != nil {
+		return err
+	}
+	deploy := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	if err := c.Patch(ctx, deploy, ctrlclient.RawPatch(types.MergePatchType, raw)); err != nil {
+		return fmt.Errorf("restart deployment %s/%s: %w", namespace, name, err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package capix
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestApplySecret(t *testing.T) {
+	ctx := context.Background()
+	c := fake.NewClientBuilder().Build()
+	labels := map[string]string{"platform.ionos.com/secret-type": "proxmox-credentials"}
+	data := map[string]string{"url": "https://pve.example:8006", "token": "cctl@pve!capi", "secret": "s1"}
+
+	steps := []struct {
+		name   string
+		secret string
+		want   bool
+	}{
+		{"create", "s1", true},
+		{"unchanged", "s1", false},
+		{"rotate", "s2", true},
+	}
+	for _, step := range steps {
+		data["secret"] = step.secret
+		changed, err := applySecret(ctx, c, "capmox-system", "capmox-manager-credentials", data, labels)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if changed != step.want {
+			t.Fatalf("%s: changed = %t, want %t", step.name, changed, step.want)
+		}
+	}
+
+	var secret corev1.Secret
+	if err := c.Get(ctx, ctrlclient.ObjectKey{Namespace: "capmox-system", Name: "capmox-manager-credentials"}, &secret); err != nil {
+		t.Fatal(err)
+	}
+	if string(secret.Data["secret"]) != "s2" || secret.Labels["platform.ionos.com/secret-type"] != "proxmox-credentials" {
+		t.Fatalf("secret = %v, labels %v", secret.Data, secret.Labels)
+	}
+}
+
+func TestRestartDeployment(t *testing.T) {
+	ctx := context.Background()
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "capmox-system", Name: "capmox-controller-manager"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{"prometheus.io/scrape": "true"}},
+		}},
+	}
+	c := fake.NewClientBuilder().WithObjects(deploy).Build()
+
+	for _, now := range []time.Time{
+		time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC),
+		time.Date(2026, 10, 17, 9, 5, 0, 0, time.UTC),
+	} {
+		if err := restartDeployment(ctx, c, "capmox-system", "capmox-controller-manager", now); err != nil {
+			t.Fatal(err)
+		}
+		var got appsv1.Deployment
+		if err := c.Get(ctx, ctrlclient.ObjectKeyFromObject(deploy), &got); err != nil {
+			t.Fatal(err)
+		}
+		annotations := got.Spec.Template.Annotations
+		if annotations[restartedAtAnnotation] != now.Format(time.RFC3339) {
+			t.Fatalf("%s = %q, want %q", restartedAtAnnotation, annotations[restartedAtAnnotation], now.Format(time.RFC3339))
+		}
+		if annotations["prometheus.io/scrape"] != "true" {
+			t.Fatalf("pod template annotations = %v, want the existing ones kept", annotations)
+		}
+	}
+
+	err := restartDeployment(ctx, c, "capmox-system", "missing", time.Now())
+	if !apierrors.IsNotFound(err) {
+		t.Fatalf("restartDeployment(missing) error = %v, want not found", err)
+	}
+}
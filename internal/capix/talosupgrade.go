@@ -0,0 +1,172 @@
+package capix
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// TemplateBaseAnnotation records the name a versioned ProxmoxMachineTemplate was derived from, so
+// that a later upgrade replaces the version suffix instead of appending another one.
+const TemplateBaseAnnotation = "cctl.zerodi.ru/template-base"
+
+// clonedFromNameAnnotation is set by Cluster API on infrastructure machines created from a template.
+const clonedFromNameAnnotation = "cluster.x-k8s.io/cloned-from-name"
+
+// TemplateRollover describes the Proxmox templates that RolloverTemplates moves the cluster to.
+type TemplateRollover struct {
+	Suffix    string           // Appended to the ProxmoxMachineTemplate names, e.g. talos-1-12-0-376567988
+	Templates map[string]int64 // VMID of the new Proxmox template per node
+}
+
+// RolloverTemplates points the cluster at new Proxmox templates. ProxmoxMachineTemplates are
+// immutable, so a copy named <base>-<suffix> is created for every template in use, with the
+// templateID of the new template on its source node (or on another node carrying it). The
+// TalosControlPlane is repointed first and its rollout awaited, then every MachineDeployment in
+// turn. opts.Timeout applies to each rollout; a remediating machine stops the rollover with
+// ErrRemediation.
+func RolloverTemplates(ctx context.Context, opts WaitOptions, to TemplateRollover) error {
+	c, err := newKubeClient(opts.Kubeconfig)
+	if err != nil {
+		return err
+	}
+	return rolloverTemplates(ctx, c, opts, to)
+}
+
+func rolloverTemplates(ctx context.Context, c ctrlclient.Client, opts WaitOptions, to TemplateRollover) error {
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultWaitTimeout
+	}
+	if opts.Interval == 0 {
+		opts.Interval = defaultWaitInterval
+	}
+	if to.Suffix == "" || len(to.Templates) == 0 {
+		return fmt.Errorf("template suffix and VMIDs are required")
+	}
+
+	name := opts.Cluster
+	cp, mds, err := clusterOwners(ctx, c, opts.Namespace, name)
+	if err != nil {
+		return err
+	}
+	if err := checkRemediation(ctx, c, opts.Namespace, name); err != nil {
+		return err
+	}
+
+	w := &clusterWatch{opts: opts, phases: map[string]string{}, ready: map[string]bool{}}
+	if err := rolloverOwner(ctx, c, w, to, cp, []string{"spec", "infrastructureTemplate", "name"},
+		ctrlclient.MatchingLabels{clusterNameLabel: name, controlPlaneLabel: ""}); err != nil {
+		return err
+	}
+	for i := range mds {
+		md := &mds[i]
+		if err := rolloverOwner(ctx, c, w, to, md, []string{"spec", "template", "spec", "infrastructureRef", "name"},
+			ctrlclient.MatchingLabels{clusterNameLabel: name, deploymentNameLabel: md.GetName()}); err != nil {
+			return err
+		}
+	}
+	log.Info().Str("cluster", name).Str("templates", to.Suffix).Msg("Template rollover complete")
+	return nil
+}
+
+// rolloverOwner copies the ProxmoxMachineTemplate referenced by field of owner and rolls the
+// owner's machines onto the copy.
+func rolloverOwner(ctx context.Context, c ctrlclient.Client, w *clusterWatch, to TemplateRollover,
+	owner *unstructured.Unstructured, field []string, machines ctrlclient.MatchingLabels) error {
+	current := newObject(ProxmoxMachineTemplateGVK)
+	key := ctrlclient.ObjectKey{Namespace: owner.GetNamespace(), Name: nestedString(owner, field...)}
+	if err := c.Get(ctx, key, current); err != nil {
+		return fmt.Errorf("get proxmoxmachinetemplate %s of %s/%s: %w", key.Name, owner.GetKind(), owner.GetName(), err)
+	}
+	next, err := ensureTemplateCopy(ctx, c, current, to)
+	if err != nil {
+		return err
+	}
+
+	r := rollout{c: c, w: w, what: "template", to: next, updated: clonedFrom(c, next)}
+	return r.run(ctx, owner, field, machines)
+}
+
+// ensureTemplateCopy creates the versioned copy of a ProxmoxMachineTemplate, or reuses it when it
+// already points at the new template, and returns its name.
+func ensureTemplateCopy(ctx context.Context, c ctrlclient.Client, current *unstructured.Unstructured, to TemplateRollover) (string, error) {
+	base := current.GetAnnotations()[TemplateBaseAnnotation]
+	if base == "" {
+		base = current.GetName()
+	}
+	name := base + "-" + to.Suffix
+
+	node, vmid, err := pickTemplate(to.Templates, nestedString(current, "spec", "template", "spec", "sourceNode"))
+	if err != nil {
+		return "", fmt.Errorf("proxmoxmachinetemplate %s: %w", current.GetName(), err)
+	}
+
+	existing := newObject(ProxmoxMachineTemplateGVK)
+	err = c.Get(ctx, ctrlclient.ObjectKey{Namespace: current.GetNamespace(), Name: name}, existing)
+	switch {
+	case err == nil:
+		got := nestedInt(existing, "spec", "template", "spec", "templateID")
+		if got != vmid {
+			return "", fmt.Errorf("proxmoxmachinetemplate %s already exists with templateID %d (want %d); delete it to continue", name, got, vmid)
+		}
+		log.Info().Str("template", name).Int64("templateID", vmid).Msg("ProxmoxMachineTemplate already exists")
+		return name, nil
+	case !apierrors.IsNotFound(err):
+		return "", fmt.Errorf("get proxmoxmachinetemplate %s: %w", name, err)
+	}
+
+	spec, _, _ := unstructured.NestedMap(current.Object, "spec")
+	obj := newObject(ProxmoxMachineTemplateGVK)
+	obj.SetNamespace(current.GetNamespace())
+	obj.SetName(name)
+	obj.SetLabels(current.GetLabels())
+	obj.SetAnnotations(map[string]string{TemplateBaseAnnotation: base})
+	obj.Object["spec"] = spec
+	if err := unstructured.SetNestedField(obj.Object, vmid, "spec", "template", "spec", "templateID"); err != nil {
+		return "", err
+	}
+	if err := unstructured.SetNestedField(obj.Object, node, "spec", "template", "spec", "sourceNode"); err != nil {
+		return "", err
+	}
+	if err := c.Create(ctx, obj, ctrlclient.FieldOwner(UpgradeFieldManager)); err != nil {
+		return "", fmt.Errorf("create proxmoxmachinetemplate %s: %w", name, err)
+	}
+	log.Info().Str("template", name).Str("node", node).Int64("templateID", vmid).Msg("Created ProxmoxMachineTemplate")
+	return name, nil
+}
+
+// pickTemplate prefers the template on the source node; otherwise the first node (by name) is used.
+func pickTemplate(templates map[string]int64, sourceNode string) (string, int64, error) {
+	if vmid, ok := templates[sourceNode]; ok {
+		return sourceNode, vmid, nil
+	}
+	nodes := make([]string, 0, len(templates))
+	for node := range templates {
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 0 {
+		return "", 0, fmt.Errorf("no template for node %s", sourceNode)
+	}
+	sort.Strings(nodes)
+	return nodes[0], templates[nodes[0]], nil
+}
+
+// clonedFrom reports whether the ProxmoxMachine of a Machine was created from the named template.
+func clonedFrom(c ctrlclient.Client, template string) func(ctx context.Context, m *unstructured.Unstructured) (bool, error) {
+	return func(ctx context.Context, m *unstructured.Unstructured) (bool, error) {
+		pm := newObject(ProxmoxMachineGVK)
+		key := ctrlclient.ObjectKey{Namespace: m.GetNamespace(), Name: nestedString(m, "spec", "infrastructureRef", "name")}
+		if err := c.Get(ctx, key, pm); err != nil {
+			if apierrors.IsNotFound(err) {
+				return false, nil
+			}
+			return false, fmt.Errorf("get proxmoxmachine %s: %w", key.Name, err)
+		}
+		return pm.GetAnnotations()[clonedFromNameAnnotation] == template, nil
+	}
+}
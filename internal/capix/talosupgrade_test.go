@@ -0,0 +1,30 @@
+package capix
+
+import "testing"
+
+func TestPickTemplate(t *testing.T) {
+	tests := []struct {
+		name       string
+		templates  map[string]int64
+		sourceNode string
+		wantNode   string
+		wantVMID   int64
+		wantErr    bool
+	}{
+		{name: "source node", templates: map[string]int64{"pve1": 901, "pve2": 902}, sourceNode: "pve2", wantNode: "pve2", wantVMID: 902},
+		{name: "first node by name", templates: map[string]int64{"pve3": 903, "pve1": 901}, sourceNode: "pve", wantNode: "pve1", wantVMID: 901},
+		{name: "no source node", templates: map[string]int64{"pve1": 901}, wantNode: "pve1", wantVMID: 901},
+		{name: "no templates", templates: map[string]int64{}, sourceNode: "pve", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, vmid, err := pickTemplate(tt.templates, tt.sourceNode)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("pickTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if node != tt.wantNode || vmid != tt.wantVMID {
+				t.Fatalf("pickTemplate() = %s, %d; want %s, %d", node, vmid, tt.wantNode, tt.wantVMID)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package capix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultWaitTimeout  = 30 * time.Minute
+	defaultWaitInterval = 5 * time.Second
+)
+
+// WaitOptions selects the workload cluster to wait for in the management cluster.
+type WaitOptions struct {
+	Kubeconfig string        // Management cluster kubeconfig (empty for the default loading rules)
+	Namespace  string        // Namespace of the Cluster API objects
+	Cluster    string        // Cluster name
+	Timeout    time.Duration // Overall timeout (default 30m)
+	Interval   time.Duration // Poll interval (default 5s)
+}
+
+// WaitForCluster polls the Cluster, TalosControlPlane, MachineDeployments and Machines of the cluster
+// until all of them are ready. Machine phase transitions are logged as they happen. When the timeout
+// expires, the returned error summarizes the conditions that are not yet satisfied.
+func WaitForCluster(ctx context.Context, opts WaitOptions) error {
+	c, err := newKubeClient(opts.Kubeconfig)
+	if err != nil {
+		return err
+	}
+	return waitForCluster(ctx, c, opts)
+}
+
+func waitForCluster(ctx context.Context, c ctrlclient.Client, opts WaitOptions) error {
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultWaitTimeout
+	}
+	if opts.Interval == 0 {
+		opts.Interval = defaultWaitInterval
+	}
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	log.Info().
+		Str("cluster", opts.Cluster).
+		Str("namespace", opts.Namespace).
+		Dur("timeout", opts.Timeout).
+		Msg("Waiting for workload cluster to become ready")
+
+	w := &clusterWatch{opts: opts, phases: map[string]string{}, ready: map[string]bool{}}
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		pending, err := w.poll(ctx, c)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			log.Warn().Err(err).Msg("Failed to read cluster status; retrying")
+		case err == nil && len(pending) == 0:
+			log.Info().Str("cluster", opts.Cluster).Msg("Workload cluster is ready")
+			return nil
+		}
+		if err == nil {
+			w.pending = pending
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return ctx.Err()
+			}
+			return w.timeoutError()
+		case <-ticker.C:
+		}
+	}
+}
+
+// clusterWatch keeps the state needed to report transitions between polls.
+type clusterWatch struct {
+	opts    WaitOptions
+	phases  map[string]string
+	ready   map[string]bool
+	pending []string
+}
+
+// poll reads the cluster objects once and returns a description of every object that is not ready.
+func (w *clusterWatch) poll(ctx context.Context, c ctrlclient.Client) ([]string, error) {
+	ns, name := w.opts.Namespace, w.opts.Cluster
+	selector := ctrlclient.MatchingLabels{clusterNameLabel: name}
+	var pending []string
+
+	cluster := newObject(ClusterGVK)
+	if err := c.Get(ctx, ctrlclient.ObjectKey{Namespace: ns, Name: name}, cluster); err != nil {
+		if apierrors.IsNotFound(err) {
+			return []string{fmt.Sprintf("Cluster/%s: not found", name)}, nil
+		}
+		return nil, err
+	}
+	pending = w.check(pending, cluster, isTrue(cluster, "Ready"))
+
+	cpName := nestedString(cluster, "spec", "controlPlaneRef", "name")
+	if cpName != "" {
+		cp := newObject(TalosControlPlaneGVK)
+		if err := c.Get(ctx, ctrlclient.ObjectKey{Namespace: ns, Name: cpName}, cp); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return nil, err
+			}
+			pending = append(pending, fmt.Sprintf("TalosControlPlane/%s: not found", cpName))
+		} else {
+			want := nestedInt(cp, "spec", "replicas")
+			ready := nestedInt(cp, "status", "readyReplicas")
+			pending = w.check(pending, cp, ready >= want && conditionOK(cp, "Ready"),
+				fmt.Sprintf("%d/%d replicas ready", ready, want))
+		}
+	}
+
+	mds := newList(MachineDeploymentGVK)
+	if err := c.List(ctx, mds, ctrlclient.InNamespace(ns), selector); err != nil {
+		return nil, err
+	}
+	for i := range mds.Items {
+		md := &mds.Items[i]
+		want := nestedInt(md, "spec", "replicas")
+		ready := nestedInt(md, "status", "readyReplicas")
+		pending = w.check(pending, md, ready >= want && conditionOK(md, "Ready"),
+			fmt.Sprintf("%d/%d replicas ready", ready, want))
+	}
+
+	machines := newList(MachineGVK)
+	if err := c.List(ctx, machines, ctrlclient.InNamespace(ns), selector); err != nil {
+		return nil, err
+	}
+	for i := range machines.Items {
+		m := &machines.Items[i]
+		phase := nestedString(m, "status", "phase")
+		w.phaseChanged(m, phase)
+		ok := phase == "Running" && nestedString(m, "status", "nodeRef", "name") != ""
+		if phase == "" {
+			phase = "Pending"
+		}
+		pending = w.check(pending, m, ok, "phase "+phase)
+	}
+	return pending, nil
+}
+
+// conditionOK treats a missing condition as satisfied, since not every provider version reports it.
+func conditionOK(obj *unstructured.Unstructured, condType string) bool {
+	c := findCondition(conditions(obj), condType)
+	return c == nil || c.Status == "True"
+}
+
+// check logs the object becoming ready and appends its summary to pending otherwise.
+func (w *clusterWatch) check(pending []string, obj *unstructured.Unstructured, ok bool, details ...string) []string {
+	ref := obj.GetKind() + "/" + obj.GetName()
+	if ok {
+		if !w.ready[ref] {
+			log.Info().Str("object", ref).Msg("Ready")
+		}
+		w.ready[ref] = true
+		return pending
+	}
+	w.ready[ref] = false
+	return append(pending, summarize(ref, obj, details...))
+}
+
+func (w *clusterWatch) phaseChanged(m *unstructured.Unstructured, phase string) {
+	name := m.GetName()
+	prev, seen := w.phases[name]
+	if seen && prev == phase {
+		return
+	}
+	w.phases[name] = phase
+	ev := log.Info().Str("machine", name).Str("phase", phase)
+	if seen {
+		ev = ev.Str("from", prev)
+	}
+	if node := nestedString(m, "status", "nodeRef", "name"); node != "" {
+		ev = ev.Str("node", node)
+	}
+	ev.Msg("Machine phase changed")
+}
+
+func (w *clusterWatch) timeoutError() error {
+	if len(w.pending) == 0 {
+		return fmt.Errorf("timed out after %s waiting for cluster %s", w.opts.Timeout, w.opts.Cluster)
+	}
+	return fmt.Errorf("timed out after %s waiting for cluster %s:\n  %s",
+		w.opts.Timeout, w.opts.Cluster, strings.Join(w.pending, "\n  "))
+}
+
+// summarize describes an object that is not ready with its non-True conditions, most severe first.
+func summarize(ref string, obj *unstructured.Unstructured, details ...string) string {
+	var parts []string
+	for _, d := range details {
+		if d != "" {
+			parts = append(parts, d)
+		}
+	}
+
+	conds := conditions(obj)
+	sort.SliceStable(conds, func(i, j int) bool { return severityRank(conds[i].Severity) < severityRank(conds[j].Severity) })
+	for _, c := range conds {
+		if c.Status != "True" {
+			parts = append(parts, c.String())
+		}
+	}
+	if len(parts) == 0 {
+		return ref + ": not ready"
+	}
+	return ref + ": " + strings.Join(parts, "; ")
+}
+
+func severityRank(severity string) int {
+	switch severity {
+	case "Error":
+		return 0
+	case "Warning":
+		return 1
+	case "Info":
+		return 2
+	}
+	return 3
+}
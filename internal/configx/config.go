@@ -28,6 +28,7 @@ type ClusterSettings struct {
 	OutDir          string
 	KubeconfigPath  string
 	TalosconfigPath string
+	ManifestPath    string
 	CiliumVersion   string
 }
 
@@ -58,6 +59,11 @@ func Cluster() ClusterSettings {
 		talosconfig = filepath.Join(out, fmt.Sprintf("talosconfig-%s", name))
 	}
 
+	manifest := viper.GetString("cluster.manifestPath")
+	if manifest == "" {
+		manifest = filepath.Join(out, fmt.Sprintf("cluster-%s.yaml", name))
+	}
+
 	ciliumVersion := viper.GetString("cluster.ciliumVersion")
 	if ciliumVersion == "" {
 		ciliumVersion = defaultCiliumVersion
@@ -69,6 +75,7 @@ func Cluster() ClusterSettings {
 		OutDir:          out,
 		KubeconfigPath:  kubeconfig,
 		TalosconfigPath: talosconfig,
+		ManifestPath:    manifest,
 		CiliumVersion:   ciliumVersion,
 	}
 }
@@ -0,0 +1,33 @@
+package configx
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/viper"
+)
+
+// Persist writes the given keys into the config file in use, leaving its other settings untouched.
+// It returns the path of the updated file.
+func Persist(values map[string]any) (string, error) {
+	path := viper.ConfigFileUsed()
+	if path == "" {
+		return "", errors.New("no config file in use; pass --config to persist settings")
+	}
+
+	file := viper.New()
+	file.SetConfigFile(path)
+	if err := file.ReadInConfig(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("read config: %w", err)
+	}
+
+	for key, value := range values {
+		file.Set(key, value)
+		viper.Set(key, value)
+	}
+	if err := file.WriteConfigAs(path); err != nil {
+		return "", fmt.Errorf("write config: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,33 @@
+package configx
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// ProviderVersions returns the provider versions pinned in capi.versions, e.g.
+//
+//	capi:
+//	  versions: ["cluster-api:v1.11.2", "talos:v0.6.7", "control-plane-talos:v0.5.8"]
+//
+// Keys are provider names, which pin every provider type with that name, or clusterctl
+// manifest labels (bootstrap-talos, control-plane-talos, infrastructure-proxmox) for a single type.
+func ProviderVersions() (map[string]string, error) {
+	versions := map[string]string{}
+	for _, entry := range viper.GetStringSlice("capi.versions") {
+		for _, pin := range strings.Split(entry, ",") {
+			pin = strings.TrimSpace(pin)
+			if pin == "" {
+				continue
+			}
+			name, version, ok := strings.Cut(pin, ":")
+			if !ok || name == "" || !strings.HasPrefix(version, "v") {
+				return nil, fmt.Errorf("invalid provider version %q in capi.versions (expected name:vX.Y.Z)", pin)
+			}
+			versions[strings.ToLower(name)] = version
+		}
+	}
+	return versions, nil
+}
@@ -0,0 +1,46 @@
+package configx
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestProviderVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		pins    []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "none", want: map[string]string{}},
+		{
+			name: "names and labels",
+			pins: []string{"cluster-api:v1.11.2", "bootstrap-talos:v0.6.7", "Control-Plane-Talos:v0.5.8"},
+			want: map[string]string{"cluster-api": "v1.11.2", "bootstrap-talos": "v0.6.7", "control-plane-talos": "v0.5.8"},
+		},
+		{
+			name: "comma separated",
+			pins: []string{"talos:v0.6.7, proxmox:v0.7.4,"},
+			want: map[string]string{"talos": "v0.6.7", "proxmox": "v0.7.4"},
+		},
+		{name: "missing version", pins: []string{"talos"}, wantErr: true},
+		{name: "version without v", pins: []string{"talos:0.6.7"}, wantErr: true},
+		{name: "missing name", pins: []string{":v0.6.7"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("capi.versions", tt.pins)
+			t.Cleanup(func() { viper.Set("capi.versions", nil) })
+
+			got, err := ProviderVersions()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ProviderVersions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !maps.Equal(got, tt.want) {
+				t.Fatalf("ProviderVersions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
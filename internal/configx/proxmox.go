@@ -0,0 +1,75 @@
+package configx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/zerodi/cctl/internal/proxmox"
+
+	"github.com/spf13/viper"
+)
+
+// Proxmox loads the Proxmox API client configuration from Viper (proxmox.* keys).
+func Proxmox() proxmox.Config {
+	cfg := proxmox.Config{
+		URL:                viper.GetString("proxmox.url"),
+		TokenID:            viper.GetString("proxmox.tokenID"),
+		Secret:             viper.GetString("proxmox.tokenSecret"),
+		Username:           viper.GetString("proxmox.username"),
+		Password:           viper.GetString("proxmox.password"),
+		Node:               viper.GetString("proxmox.node"),
+		Nodes:              viper.GetStringSlice("proxmox.nodes"),
+		AllowedNodes:       AllowedNodes(),
+		ISOStorage:         viper.GetString("proxmox.isoStorage"),
+		SchematicFile:      viper.GetString("proxmox.schematicFile"),
+		TalosSchematicPath: viper.GetString("proxmox.schematicYAML"),
+		TemplateJSONPath:   viper.GetString("proxmox.templateJSON"),
+		SkipTLSVerify:      viper.GetBool("proxmox.skipTLSVerify"),
+		CAFile:             viper.GetString("proxmox.caFile"),
+		Fingerprint:        viper.GetString("proxmox.fingerprint"),
+		TaskTimeout:        viper.GetDuration("proxmox.taskTimeout"),
+		Retries:            viper.GetInt("proxmox.retries"),
+		RetryMaxElapsed:    viper.GetDuration("proxmox.retryMaxElapsed"),
+	}
+	if timeout := viper.GetDuration("proxmox.httpTimeout"); timeout > 0 {
+		cfg.Timeout = timeout
+	}
+	return cfg
+}
+
+// AllowedNodes returns the Proxmox nodes the workload cluster may use: proxmox.allowedNodes, or else
+// cluster.spec.proxmox.allowedNodes. Empty means every node.
+func AllowedNodes() []string {
+	if nodes := viper.GetStringSlice("proxmox.allowedNodes"); len(nodes) > 0 {
+		return nodes
+	}
+	return viper.GetStringSlice("cluster.spec.proxmox.allowedNodes")
+}
+
+// TemplateValues returns the template JSON values configured under proxmox.* (see proxmox create-template).
+func TemplateValues() proxmox.TemplateValues {
+	return proxmox.TemplateValues{
+		"talosVersion": strings.TrimPrefix(viper.GetString("proxmox.talosVersion"), "v"),
+		"isoStorage":   viper.GetString("proxmox.isoStorage"),
+		"diskStorage":  viper.GetString("proxmox.diskStorage"),
+		"bridge":       viper.GetString("proxmox.bridge"),
+		"vmid":         viper.GetString("proxmox.templateVMID"),
+	}
+}
+
+// TemplateOptions returns the template placement options from proxmox.templateVersioned and
+// proxmox.templateVMIDRange ("min-max").
+func TemplateOptions() (proxmox.TemplateOptions, error) {
+	opts := proxmox.TemplateOptions{Versioned: viper.GetBool("proxmox.templateVersioned")}
+	if r := viper.GetString("proxmox.templateVMIDRange"); r != "" {
+		lo, hi, ok := strings.Cut(r, "-")
+		minID, errMin := strconv.ParseInt(strings.TrimSpace(lo), 10, 64)
+		maxID, errMax := strconv.ParseInt(strings.TrimSpace(hi), 10, 64)
+		if !ok || errMin != nil || errMax != nil {
+			return opts, fmt.Errorf("invalid VMID range %q (expected min-max)", r)
+		}
+		opts.MinVMID, opts.MaxVMID = minID, maxID
+	}
+	return opts, nil
+}
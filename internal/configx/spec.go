@@ -0,0 +1,229 @@
+package configx
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultKubernetesVersion = "v1.34.0"
+	defaultEndpointPort      = 6443
+	defaultIPv4Prefix        = 24
+	defaultInterface         = "eth0"
+	defaultBridge            = "vmbr0"
+	defaultTemplateID        = 900
+	defaultWorkerPool        = "workers"
+	defaultUnhealthyTimeout  = 5 * time.Minute
+	defaultNodeStartup       = 15 * time.Minute
+	defaultWorkerMaxUnhealth = "40%"
+)
+
+// ClusterSpec describes the workload cluster rendered by `capi generate`. It is read from the
+// cluster.spec section of the cctl config; zero values fall back to defaults and to the proxmox.* settings.
+type ClusterSpec struct {
+	KubernetesVersion    string
+	ControlPlaneEndpoint EndpointSpec
+	Network              NetworkSpec
+	Proxmox              ProxmoxSpec
+	ControlPlane         MachinePoolSpec
+	Workers              []MachinePoolSpec
+	HealthChecks         HealthCheckSpec
+}
+
+// EndpointSpec is the control plane endpoint, served by the Talos VIP.
+type EndpointSpec struct {
+	Host string
+	Port int
+}
+
+// NetworkSpec holds the cluster and node network layout.
+type NetworkSpec struct {
+	PodCIDRs     []string
+	ServiceCIDRs []string
+	DNSServers   []string
+	Interface    string
+	IPv4Pool     IPPoolSpec
+}
+
+// IPPoolSpec is the static IPv4 pool CAPMOX assigns node addresses from.
+type IPPoolSpec struct {
+	Addresses []string // Ranges (192.168.100.210-192.168.100.219), CIDRs or single addresses
+	Gateway   string
+	Prefix    int
+}
+
+// ProxmoxSpec selects where and from which template machines are cloned.
+type ProxmoxSpec struct {
+	AllowedNodes []string
+	SourceNode   string
+	TemplateID   int
+	Bridge       string
+}
+
+// MachinePoolSpec sizes the control plane or a worker pool.
+type MachinePoolSpec struct {
+	Name      string // Worker pools only; the MachineDeployment is named <cluster>-<name>
+	Replicas  int
+	Cores     int
+	Sockets   int
+	MemoryMiB int
+	DiskGB    int
+}
+
+// HealthCheckSpec configures the generated MachineHealthChecks.
+type HealthCheckSpec struct {
+	Disabled           bool
+	UnhealthyTimeout   time.Duration
+	NodeStartupTimeout time.Duration
+	WorkerMaxUnhealthy string
+}
+
+// LoadClusterSpec reads cluster.spec from Viper, applies defaults and validates the result.
+func LoadClusterSpec() (ClusterSpec, error) {
+	var spec ClusterSpec
+	if err := viper.UnmarshalKey("cluster.spec", &spec); err != nil {
+		return spec, fmt.Errorf("parse cluster.spec: %w", err)
+	}
+	spec.applyDefaults()
+	return spec, spec.Validate()
+}
+
+// WorkerPools returns the worker pool names of cluster.spec (with the default pool when none is
+// configured) without validating the rest of the spec.
+func WorkerPools() ([]string, error) {
+	var spec ClusterSpec
+	if err := viper.UnmarshalKey("cluster.spec", &spec); err != nil {
+		return nil, fmt.Errorf("parse cluster.spec: %w", err)
+	}
+	spec.applyDefaults()
+	pools := make([]string, 0, len(spec.Workers))
+	for _, w := range spec.Workers {
+		if w.Name != "" {
+			pools = append(pools, w.Name)
+		}
+	}
+	return pools, nil
+}
+
+func (s *ClusterSpec) applyDefaults() {
+	if s.KubernetesVersion == "" {
+		s.KubernetesVersion = defaultKubernetesVersion
+	}
+	if !strings.HasPrefix(s.KubernetesVersion, "v") {
+		s.KubernetesVersion = "v" + s.KubernetesVersion
+	}
+	if s.ControlPlaneEndpoint.Port == 0 {
+		s.ControlPlaneEndpoint.Port = defaultEndpointPort
+	}
+
+	n := &s.Network
+	if len(n.PodCIDRs) == 0 {
+		n.PodCIDRs = []string{"10.244.0.0/16"}
+	}
+	if len(n.ServiceCIDRs) == 0 {
+		n.ServiceCIDRs = []string{"10.96.0.0/12"}
+	}
+	if len(n.DNSServers) == 0 {
+		n.DNSServers = []string{"8.8.8.8", "8.8.4.4"}
+	}
+	if n.Interface == "" {
+		n.Interface = defaultInterface
+	}
+	if n.IPv4Pool.Prefix == 0 {
+		n.IPv4Pool.Prefix = defaultIPv4Prefix
+	}
+
+	p := &s.Proxmox
+	if p.SourceNode == "" {
+		p.SourceNode = viper.GetString("proxmox.node")
+	}
+	if len(p.AllowedNodes) == 0 && p.SourceNode != "" {
+		p.AllowedNodes = []string{p.SourceNode}
+	}
+	if p.TemplateID == 0 {
+		p.TemplateID = viper.GetInt("proxmox.templateVMID")
+	}
+	if p.TemplateID == 0 {
+		p.TemplateID = defaultTemplateID
+	}
+	if p.Bridge == "" {
+		p.Bridge = viper.GetString("proxmox.bridge")
+	}
+	if p.Bridge == "" {
+		p.Bridge = defaultBridge
+	}
+
+	s.ControlPlane.applyDefaults(MachinePoolSpec{Replicas: 1, Cores: 2, Sockets: 1, MemoryMiB: 4096, DiskGB: 40})
+	if len(s.Workers) == 0 {
+		s.Workers = []MachinePoolSpec{{Name: defaultWorkerPool}}
+	}
+	for i := range s.Workers {
+		s.Workers[i].applyDefaults(MachinePoolSpec{Replicas: 2, Cores: 4, Sockets: 1, MemoryMiB: 4096, DiskGB: 80})
+	}
+
+	h := &s.HealthChecks
+	if h.UnhealthyTimeout == 0 {
+		h.UnhealthyTimeout = defaultUnhealthyTimeout
+	}
+	if h.NodeStartupTimeout == 0 {
+		h.NodeStartupTimeout = defaultNodeStartup
+	}
+	if h.WorkerMaxUnhealthy == "" {
+		h.WorkerMaxUnhealthy = defaultWorkerMaxUnhealth
+	}
+}
+
+func (m *MachinePoolSpec) applyDefaults(def MachinePoolSpec) {
+	if m.Replicas == 0 {
+		m.Replicas = def.Replicas
+	}
+	if m.Cores == 0 {
+		m.Cores = def.Cores
+	}
+	if m.Sockets == 0 {
+		m.Sockets = def.Sockets
+	}
+	if m.MemoryMiB == 0 {
+		m.MemoryMiB = def.MemoryMiB
+	}
+	if m.DiskGB == 0 {
+		m.DiskGB = def.DiskGB
+	}
+}
+
+// Validate checks the site-specific settings that have no sensible default.
+func (s ClusterSpec) Validate() error {
+	var errs []error
+	if s.ControlPlaneEndpoint.Host == "" {
+		errs = append(errs, errors.New("cluster.spec.controlPlaneEndpoint.host (the control plane VIP) is required"))
+	}
+	if len(s.Network.IPv4Pool.Addresses) == 0 {
+		errs = append(errs, errors.New("cluster.spec.network.ipv4Pool.addresses is required"))
+	}
+	if s.Network.IPv4Pool.Gateway == "" {
+		errs = append(errs, errors.New("cluster.spec.network.ipv4Pool.gateway is required"))
+	}
+	if s.Proxmox.SourceNode == "" {
+		errs = append(errs, errors.New("cluster.spec.proxmox.sourceNode (or proxmox.node) is required"))
+	}
+	if s.ControlPlane.Replicas%2 == 0 {
+		errs = append(errs, fmt.Errorf("control plane replicas must be odd for etcd quorum, got %d", s.ControlPlane.Replicas))
+	}
+
+	seen := make(map[string]struct{}, len(s.Workers))
+	for i, w := range s.Workers {
+		if w.Name == "" {
+			errs = append(errs, fmt.Errorf("cluster.spec.workers[%d].name is required", i))
+			continue
+		}
+		if _, dup := seen[w.Name]; dup {
+			errs = append(errs, fmt.Errorf("duplicate worker pool %q", w.Name))
+		}
+		seen[w.Name] = struct{}{}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,171 @@
+package configx
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// validSpec is the minimal cluster.spec that passes validation once proxmox.node is set.
+func validSpec() map[string]any {
+	return map[string]any{
+		"controlPlaneEndpoint": map[string]any{"host": "192.168.100.200"},
+		"network": map[string]any{
+			"ipv4Pool": map[string]any{
+				"addresses": []string{"192.168.100.210-192.168.100.219"},
+				"gateway":   "192.168.100.1",
+			},
+		},
+	}
+}
+
+func TestLoadClusterSpecValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		node    string
+		edit    func(spec map[string]any)
+		wantErr []string
+	}{
+		{name: "valid", node: "pve1"},
+		{
+			name:    "even control plane",
+			node:    "pve1",
+			edit:    func(s map[string]any) { s["controlPlane"] = map[string]any{"replicas": 2} },
+			wantErr: []string{"control plane replicas must be odd for etcd quorum, got 2"},
+		},
+		{
+			name:    "missing endpoint host",
+			node:    "pve1",
+			edit:    func(s map[string]any) { delete(s, "controlPlaneEndpoint") },
+			wantErr: []string{"controlPlaneEndpoint.host"},
+		},
+		{
+			name:    "missing pool",
+			node:    "pve1",
+			edit:    func(s map[string]any) { delete(s, "network") },
+			wantErr: []string{"ipv4Pool.addresses", "ipv4Pool.gateway"},
+		},
+		{
+			name:    "missing source node",
+			wantErr: []string{"proxmox.sourceNode"},
+		},
+		{
+			name: "source node from spec",
+			edit: func(s map[string]any) { s["proxmox"] = map[string]any{"sourceNode": "pve2"} },
+		},
+		{
+			name: "unnamed and duplicate workers",
+			node: "pve1",
+			edit: func(s map[string]any) {
+				s["workers"] = []map[string]any{{"name": "gpu"}, {"replicas": 1}, {"name": "gpu"}}
+			},
+			wantErr: []string{"cluster.spec.workers[1].name is required", `duplicate worker pool "gpu"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := validSpec()
+			if tt.edit != nil {
+				tt.edit(spec)
+			}
+			viper.Set("cluster.spec", spec)
+			viper.Set("proxmox.node", tt.node)
+			t.Cleanup(func() {
+				viper.Set("cluster.spec", nil)
+				viper.Set("proxmox.node", nil)
+			})
+
+			_, err := LoadClusterSpec()
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("LoadClusterSpec() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("LoadClusterSpec() error = nil, want %q", tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("LoadClusterSpec() error = %q, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadClusterSpecDefaults(t *testing.T) {
+	spec := validSpec()
+	spec["kubernetesVersion"] = "1.33.4"
+	spec["workers"] = []map[string]any{{"name": "gpu", "cores": 8}}
+	viper.Set("cluster.spec", spec)
+	viper.Set("proxmox.node", "pve1")
+	viper.Set("proxmox.templateVMID", 9001)
+	t.Cleanup(func() {
+		viper.Set("cluster.spec", nil)
+		viper.Set("proxmox.node", nil)
+		viper.Set("proxmox.templateVMID", nil)
+	})
+
+	got, err := LoadClusterSpec()
+	if err != nil {
+		t.Fatalf("LoadClusterSpec() error = %v", err)
+	}
+	if got.KubernetesVersion != "v1.33.4" {
+		t.Errorf("KubernetesVersion = %q, want v1.33.4", got.KubernetesVersion)
+	}
+	if got.ControlPlaneEndpoint.Port != defaultEndpointPort {
+		t.Errorf("ControlPlaneEndpoint.Port = %d, want %d", got.ControlPlaneEndpoint.Port, defaultEndpointPort)
+	}
+	if got.Network.IPv4Pool.Prefix != defaultIPv4Prefix || got.Network.Interface != defaultInterface {
+		t.Errorf("Network = %+v, want prefix %d and interface %s", got.Network, defaultIPv4Prefix, defaultInterface)
+	}
+	p := got.Proxmox
+	if p.SourceNode != "pve1" || !slices.Equal(p.AllowedNodes, []string{"pve1"}) || p.TemplateID != 9001 || p.Bridge != defaultBridge {
+		t.Errorf("Proxmox = %+v, want source and allowed node pve1, template 9001, bridge %s", p, defaultBridge)
+	}
+	if cp := got.ControlPlane; cp.Replicas != 1 || cp.Cores != 2 || cp.DiskGB != 40 {
+		t.Errorf("ControlPlane = %+v, want 1 replica, 2 cores, 40 GB", cp)
+	}
+	if len(got.Workers) != 1 {
+		t.Fatalf("Workers = %+v, want the gpu pool only", got.Workers)
+	}
+	if w := got.Workers[0]; w.Name != "gpu" || w.Replicas != 2 || w.Cores != 8 || w.MemoryMiB != 4096 {
+		t.Errorf("Workers[0] = %+v, want gpu with 2 replicas, 8 cores, 4096 MiB", w)
+	}
+	if hc := got.HealthChecks; hc.UnhealthyTimeout != defaultUnhealthyTimeout || hc.WorkerMaxUnhealthy != defaultWorkerMaxUnhealth {
+		t.Errorf("HealthChecks = %+v, want defaults", hc)
+	}
+}
+
+func TestWorkerPools(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers []map[string]any
+		want    []string
+	}{
+		{name: "default pool", want: []string{defaultWorkerPool}},
+		{name: "configured pools", workers: []map[string]any{{"name": "gpu"}, {"name": "storage"}}, want: []string{"gpu", "storage"}},
+		{name: "unnamed pool skipped", workers: []map[string]any{{"name": "gpu"}, {"replicas": 1}}, want: []string{"gpu"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := map[string]any{}
+			if tt.workers != nil {
+				spec["workers"] = tt.workers
+			}
+			viper.Set("cluster.spec", spec)
+			t.Cleanup(func() { viper.Set("cluster.spec", nil) })
+
+			got, err := WorkerPools()
+			if err != nil {
+				t.Fatalf("WorkerPools() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("WorkerPools() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,9 +1,12 @@
 package executil
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
@@ -50,3 +53,15 @@ func RunCapture(ctx context.Context, env []string, name string, args ...string)
 	err := cmd.Run()
 	return stdout.String(), stderr.String(), err
 }
+
+// Confirm asks question on out and reports whether the answer read from in is yes.
+// An empty answer or end of input means no.
+func Confirm(in io.Reader, out io.Writer, question string) (bool, error) {
+	fmt.Fprintf(out, "%s [y/N]: ", question)
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, fmt.Errorf("read confirmation: %w", err)
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
@@ -0,0 +1,28 @@
+// Package exitx maps errors that carry a meaning beyond failure to process exit codes.
+package exitx
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Exit codes of cctl.
+const (
+	Failure = 1
+	Changes = 2 // `capi diff` and `capi deploy --dry-run` found objects that would change
+)
+
+// ChangesError reports that a diff or dry run found objects that would change.
+type ChangesError struct{ Changed int }
+
+func (e ChangesError) Error() string {
+	return fmt.Sprintf("%d object(s) would change", e.Changed)
+}
+
+// Code returns the exit code for err: Changes for a ChangesError, Failure otherwise.
+func Code(err error) int {
+	if errors.As(err, new(ChangesError)) {
+		return Changes
+	}
+	return Failure
+}
@@ -0,0 +1,181 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultBaseDelay = 500 * time.Millisecond
+	defaultMaxDelay  = 15 * time.Second
+	peekLimit        = 2048
+)
+
+// RetryPolicy configures how RetryTransport retries failed requests.
+type RetryPolicy struct {
+	MaxRetries int           // Number of retries after the first attempt; 0 disables retries
+	MaxElapsed time.Duration // Stop retrying once this much time has passed since the first attempt (0 = unlimited)
+	BaseDelay  time.Duration // Initial backoff delay (default 500ms)
+	MaxDelay   time.Duration // Upper bound for a single backoff delay (default 15s)
+}
+
+// RetryTransport retries idempotent requests on transient failures with exponential backoff and jitter.
+// Transient failures are transport errors, 429, 502, 503, 504 and Proxmox "got timeout" 500 responses
+// (returned while a cluster lock is busy). Retry-After headers are honoured.
+type RetryTransport struct {
+	Base   http.RoundTripper
+	Policy RetryPolicy
+}
+
+// NewRetryTransport wraps base (http.DefaultTransport if nil) with the retry policy.
+func NewRetryTransport(base http.RoundTripper, policy RetryPolicy) *RetryTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &RetryTransport{Base: base, Policy: policy}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.Policy.MaxRetries <= 0 || !replayable(req) {
+		return t.Base.RoundTrip(req)
+	}
+
+	start := time.Now()
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("rewind request body: %w", err)
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		resp, err := t.Base.RoundTrip(req)
+		retry, reason := shouldRetry(req.Context(), resp, err)
+		if !retry || attempt >= t.Policy.MaxRetries {
+			return resp, err
+		}
+
+		delay := t.backoff(attempt)
+		if resp != nil {
+			if after, ok := retryAfter(resp); ok {
+				delay = after
+			}
+			io.Copy(io.Discard, io.LimitReader(resp.Body, peekLimit))
+			resp.Body.Close()
+		}
+		if t.Policy.MaxElapsed > 0 && time.Since(start)+delay > t.Policy.MaxElapsed {
+			log.Debug().Str("url", req.URL.Redacted()).Msg("Retry budget exhausted")
+			if err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%s %s: %s (retry budget of %s exhausted)", req.Method, req.URL.Redacted(), reason, t.Policy.MaxElapsed)
+		}
+
+		log.Warn().
+			Int("attempt", attempt+1).
+			Int("maxRetries", t.Policy.MaxRetries).
+			Str("method", req.Method).
+			Str("url", req.URL.Redacted()).
+			Str("reason", reason).
+			Dur("delay", delay.Round(time.Millisecond)).
+			Msg("Retrying HTTP request")
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (t *RetryTransport) backoff(attempt int) time.Duration {
+	base := t.Policy.BaseDelay
+	if base <= 0 {
+		base = defaultBaseDelay
+	}
+	maxDelay := t.Policy.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = defaultMaxDelay
+	}
+
+	delay := base << attempt
+	if delay <= 0 || delay > maxDelay {
+		delay = maxDelay
+	}
+	// Equal jitter: half fixed, half random.
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// replayable reports whether the request is idempotent and its body can be sent again.
+// Like net/http, requests carrying an Idempotency-Key header are treated as idempotent.
+func replayable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+	default:
+		_, hasKey := req.Header["Idempotency-Key"]
+		_, hasXKey := req.Header["X-Idempotency-Key"]
+		if !hasKey && !hasXKey {
+			return false
+		}
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+func shouldRetry(ctx context.Context, resp *http.Response, err error) (bool, string) {
+	if err != nil {
+		if ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return false, ""
+		}
+		return true, err.Error()
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true, resp.Status
+	case http.StatusInternalServerError:
+		// Proxmox reports busy cluster locks as "500 got timeout" / "can't lock file ... got timeout".
+		if strings.Contains(strings.ToLower(resp.Status), "got timeout") {
+			return true, resp.Status
+		}
+		peek, _ := io.ReadAll(io.LimitReader(resp.Body, peekLimit))
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(peek), resp.Body), resp.Body}
+		if bytes.Contains(bytes.ToLower(peek), []byte("got timeout")) {
+			return true, resp.Status + ": got timeout"
+		}
+	}
+	return false, ""
+}
+
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	v := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,164 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tr := &RetryTransport{Policy: RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}}
+	tests := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{0, 50 * time.Millisecond, 100 * time.Millisecond},
+		{1, 100 * time.Millisecond, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond, 800 * time.Millisecond},
+		{4, 500 * time.Millisecond, time.Second},  // capped at MaxDelay
+		{70, 500 * time.Millisecond, time.Second}, // shift overflow
+	}
+	for _, tt := range tests {
+		for range 50 {
+			if d := tr.backoff(tt.attempt); d < tt.min || d > tt.max {
+				t.Fatalf("backoff(%d) = %s, want within [%s, %s]", tt.attempt, d, tt.min, tt.max)
+			}
+		}
+	}
+}
+
+func TestShouldRetry(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	resp := func(code int, status, body string) *http.Response {
+		return &http.Response{StatusCode: code, Status: status, Body: io.NopCloser(strings.NewReader(body))}
+	}
+	tests := []struct {
+		name string
+		ctx  context.Context
+		resp *http.Response
+		err  error
+		want bool
+	}{
+		{name: "transport error", ctx: context.Background(), err: errors.New("connection reset"), want: true},
+		{name: "canceled", ctx: canceled, err: context.Canceled},
+		{name: "deadline", ctx: context.Background(), err: context.DeadlineExceeded},
+		{name: "ok", ctx: context.Background(), resp: resp(200, "200 OK", "")},
+		{name: "not found", ctx: context.Background(), resp: resp(404, "404 Not Found", "")},
+		{name: "too many requests", ctx: context.Background(), resp: resp(429, "429 Too Many Requests", ""), want: true},
+		{name: "bad gateway", ctx: context.Background(), resp: resp(502, "502 Bad Gateway", ""), want: true},
+		{name: "unavailable", ctx: context.Background(), resp: resp(503, "503 Service Unavailable", ""), want: true},
+		{name: "lock timeout in status", ctx: context.Background(), resp: resp(500, "500 got timeout", ""), want: true},
+		{name: "lock timeout in body", ctx: context.Background(), resp: resp(500, "500 Internal Server Error", "can't lock file '/var/lock/qemu-server/lock-900.conf' - got timeout"), want: true},
+		{name: "other server error", ctx: context.Background(), resp: resp(500, "500 Internal Server Error", "boom")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := shouldRetry(tt.ctx, tt.resp, tt.err); got != tt.want {
+				t.Fatalf("shouldRetry() = %t, want %t", got, tt.want)
+			}
+			if tt.resp != nil {
+				// The peeked body must still be readable by the caller.
+				body, _ := io.ReadAll(tt.resp.Body)
+				if tt.name == "other server error" && string(body) != "boom" {
+					t.Fatalf("body = %q after peek, want %q", body, "boom")
+				}
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{"", 0, false},
+		{"3", 3 * time.Second, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0, true},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		if tt.header != "" {
+			resp.Header.Set("Retry-After", tt.header)
+		}
+		got, ok := retryAfter(resp)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("retryAfter(%q) = %s, %t; want %s, %t", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestReplayable(t *testing.T) {
+	tests := []struct {
+		method string
+		key    bool
+		want   bool
+	}{
+		{http.MethodGet, false, true},
+		{http.MethodDelete, false, true},
+		{http.MethodPost, false, false},
+		{http.MethodPost, true, true},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "https://pve.example/api2/json", nil)
+		if tt.key {
+			req.Header["Idempotency-Key"] = nil
+		}
+		if got := replayable(req); got != tt.want {
+			t.Errorf("replayable(%s, key=%t) = %t, want %t", tt.method, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestRoundTripRetries(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	policy := RetryPolicy{MaxRetries: 4, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+	client := &http.Client{Transport: NewRetryTransport(nil, policy)}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || calls.Load() != 3 {
+		t.Fatalf("status %d after %d calls, want 200 after 3", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestRoundTripMaxElapsed(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	policy := RetryPolicy{MaxRetries: 10, MaxElapsed: 100 * time.Millisecond}
+	client := &http.Client{Transport: NewRetryTransport(nil, policy)}
+	_, err := client.Get(srv.URL)
+	if err == nil || !strings.Contains(err.Error(), "retry budget") {
+		t.Fatalf("err = %v, want retry budget exhausted", err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("%d calls, want 1 (Retry-After exceeds the budget)", calls.Load())
+	}
+}
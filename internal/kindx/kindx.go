@@ -1,18 +1,25 @@
 package kindx
 
 import (
-	"os"
 	"time"
 
+	"github.com/zerodi/cctl/config"
+
 	"github.com/rs/zerolog/log"
 	"sigs.k8s.io/kind/pkg/cluster"
 )
 
+// Create creates the kind cluster. A missing config at the default location falls back to the
+// embedded kind.yaml; any other missing config leaves kind's defaults in place.
 func Create(name, configPath string) error {
 	provider := cluster.NewProvider()
 	opts := []cluster.CreateOption{}
-	if st, err := os.Stat(configPath); err == nil && !st.IsDir() {
-		opts = append(opts, cluster.CreateWithConfigFile(configPath))
+	if configPath != "" {
+		if raw, err := config.ReadFile(configPath); err == nil {
+			opts = append(opts, cluster.CreateWithRawConfig(raw))
+		} else {
+			log.Warn().Err(err).Str("config", configPath).Msg("kind config not readable; using kind defaults")
+		}
 	}
 	// Wait briefly for nodes to become ready
 	opts = append(opts, cluster.CreateWithWaitForReady(2*time.Minute))
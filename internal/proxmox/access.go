@@ -0,0 +1,180 @@
+package proxmox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// CAPMOXPrivileges is the least-privilege set the Cluster API Proxmox provider needs to clone and manage VMs.
+var CAPMOXPrivileges = []string{
+	"Datastore.AllocateSpace",
+	"Datastore.Audit",
+	"Pool.Audit",
+	"SDN.Use",
+	"Sys.Audit",
+	"Sys.Console",
+	"VM.Allocate",
+	"VM.Audit",
+	"VM.Clone",
+	"VM.Config.CDROM",
+	"VM.Config.CPU",
+	"VM.Config.Cloudinit",
+	"VM.Config.Disk",
+	"VM.Config.HWType",
+	"VM.Config.Memory",
+	"VM.Config.Network",
+	"VM.Config.Options",
+	"VM.Migrate",
+	"VM.PowerMgmt",
+}
+
+// TokenOptions describes the user, role and API token created by CreateAPIToken.
+type TokenOptions struct {
+	UserID     string   // e.g. capmox@pve
+	RoleID     string   // e.g. CAPMOX
+	TokenName  string   // e.g. capi
+	Privileges []string // Defaults to CAPMOXPrivileges
+	Path       string   // ACL path the role is granted on (default "/")
+	Rotate     bool     // Replace an existing token with the same name
+}
+
+// APIToken is a newly created Proxmox API token. The secret is only returned once by Proxmox.
+type APIToken struct {
+	ID     string `json:"tokenID"`
+	Secret string `json:"secret"`
+}
+
+// CreateAPIToken ensures the role, user and ACL exist and creates a privilege-separation-free API token
+// for the user. Requires credentials allowed to manage users (e.g. root@pam via ticket authentication).
+func (c *Client) CreateAPIToken(ctx context.Context, opts TokenOptions) (*APIToken, error) {
+	if opts.UserID == "" || opts.RoleID == "" || opts.TokenName == "" {
+		return nil, errors.New("user, role and token name are required")
+	}
+	if !strings.Contains(opts.UserID, "@") {
+		return nil, fmt.Errorf("user %q must include a realm (e.g. %s@pve)", opts.UserID, opts.UserID)
+	}
+	privs := opts.Privileges
+	if len(privs) == 0 {
+		privs = CAPMOXPrivileges
+	}
+	aclPath := opts.Path
+	if aclPath == "" {
+		aclPath = "/"
+	}
+
+	if err := c.ensureRole(ctx, opts.RoleID, privs); err != nil {
+		return nil, err
+	}
+	if err := c.ensureUser(ctx, opts.UserID); err != nil {
+		return nil, err
+	}
+
+	acl := url.Values{"path": {aclPath}, "roles": {opts.RoleID}, "users": {opts.UserID}, "propagate": {"1"}}
+	if err := c.sendProxmox(ctx, http.MethodPut, "/access/acl", acl, nil); err != nil {
+		return nil, fmt.Errorf("grant role %s to %s on %s: %w", opts.RoleID, opts.UserID, aclPath, err)
+	}
+	log.Info().Str("user", opts.UserID).Str("role", opts.RoleID).Str("path", aclPath).Msg("Granted Proxmox role")
+
+	tokenPath := fmt.Sprintf("/access/users/%s/token/%s", url.PathEscape(opts.UserID), url.PathEscape(opts.TokenName))
+	exists, err := c.tokenExists(ctx, opts.UserID, opts.TokenName)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		if !opts.Rotate {
+			return nil, fmt.Errorf("token %s!%s already exists (use --rotate to replace it)", opts.UserID, opts.TokenName)
+		}
+		if err := c.sendProxmox(ctx, http.MethodDelete, tokenPath, nil, nil); err != nil {
+			return nil, fmt.Errorf("delete token %s!%s: %w", opts.UserID, opts.TokenName, err)
+		}
+		log.Info().Str("token", opts.UserID+"!"+opts.TokenName).Msg("Deleted existing API token")
+	}
+
+	var created struct {
+		FullTokenID string `json:"full-tokenid"`
+		Value       string `json:"value"`
+	}
+	form := url.Values{"privsep": {"0"}, "comment": {"Cluster API Proxmox provider (managed by cctl)"}}
+	if err := c.sendProxmox(ctx, http.MethodPost, tokenPath, form, &created); err != nil {
+		return nil, fmt.Errorf("create token %s!%s: %w", opts.UserID, opts.TokenName, err)
+	}
+	if created.Value == "" {
+		return nil, errors.New("proxmox did not return the token secret")
+	}
+	if created.FullTokenID == "" {
+		created.FullTokenID = opts.UserID + "!" + opts.TokenName
+	}
+
+	log.Info().Str("token", created.FullTokenID).Msg("Created Proxmox API token")
+	return &APIToken{ID: created.FullTokenID, Secret: created.Value}, nil
+}
+
+func (c *Client) ensureRole(ctx context.Context, roleID string, privs []string) error {
+	var roles []struct {
+		RoleID string `json:"roleid"`
+	}
+	if err := c.getProxmox(ctx, "/access/roles", nil, &roles); err != nil {
+		return fmt.Errorf("list roles: %w", err)
+	}
+
+	form := url.Values{"privs": {strings.Join(privs, ",")}}
+	for _, r := range roles {
+		if r.RoleID == roleID {
+			if err := c.sendProxmox(ctx, http.MethodPut, "/access/roles/"+url.PathEscape(roleID), form, nil); err != nil {
+				return fmt.Errorf("update role %s: %w", roleID, err)
+			}
+			log.Info().Str("role", roleID).Msg("Updated Proxmox role privileges")
+			return nil
+		}
+	}
+
+	form.Set("roleid", roleID)
+	if err := c.sendProxmox(ctx, http.MethodPost, "/access/roles", form, nil); err != nil {
+		return fmt.Errorf("create role %s: %w", roleID, err)
+	}
+	log.Info().Str("role", roleID).Msg("Created Proxmox role")
+	return nil
+}
+
+func (c *Client) ensureUser(ctx context.Context, userID string) error {
+	var users []struct {
+		UserID string `json:"userid"`
+	}
+	if err := c.getProxmox(ctx, "/access/users", nil, &users); err != nil {
+		return fmt.Errorf("list users: %w", err)
+	}
+	for _, u := range users {
+		if u.UserID == userID {
+			return nil
+		}
+	}
+
+	form := url.Values{"userid": {userID}, "comment": {"Cluster API Proxmox provider (managed by cctl)"}}
+	if err := c.sendProxmox(ctx, http.MethodPost, "/access/users", form, nil); err != nil {
+		return fmt.Errorf("create user %s: %w", userID, err)
+	}
+	log.Info().Str("user", userID).Msg("Created Proxmox user")
+	return nil
+}
+
+func (c *Client) tokenExists(ctx context.Context, userID, tokenName string) (bool, error) {
+	var tokens []struct {
+		TokenID string `json:"tokenid"`
+	}
+	path := fmt.Sprintf("/access/users/%s/token", url.PathEscape(userID))
+	if err := c.getProxmox(ctx, path, nil, &tokens); err != nil {
+		return false, fmt.Errorf("list tokens of %s: %w", userID, err)
+	}
+	for _, t := range tokens {
+		if t.TokenID == tokenName {
+			return true, nil
+		}
+	}
+	return false, nil
+}
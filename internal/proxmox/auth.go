@@ -0,0 +1,103 @@
+package proxmox
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Proxmox tickets are valid for two hours; renew well before that.
+const ticketRenewAfter = 90 * time.Minute
+
+// ticketAuth holds the PVEAuthCookie ticket and CSRF token obtained from /access/ticket.
+// It is shared by all node-scoped copies of a Client.
+type ticketAuth struct {
+	username string
+	password string
+
+	mu     sync.Mutex
+	ticket string
+	csrf   string
+	issued time.Time
+}
+
+// authorize adds credentials to the request, logging in or renewing the ticket when needed.
+func (c *Client) authorize(req *http.Request) error {
+	if c.auth == nil {
+		req.Header.Set("Authorization", fmt.Sprintf("PVEAPIToken=%s=%s", c.tokenID, c.secret))
+		return nil
+	}
+
+	ticket, csrf, err := c.ensureTicket(req.Context())
+	if err != nil {
+		return err
+	}
+	req.AddCookie(&http.Cookie{Name: "PVEAuthCookie", Value: ticket})
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		req.Header.Set("CSRFPreventionToken", csrf)
+	}
+	return nil
+}
+
+func (c *Client) ensureTicket(ctx context.Context) (string, string, error) {
+	a := c.auth
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.ticket != "" && time.Since(a.issued) < ticketRenewAfter {
+		return a.ticket, a.csrf, nil
+	}
+
+	if a.ticket != "" {
+		// An existing ticket can be renewed by passing it as the password.
+		if err := c.requestTicket(ctx, a.ticket); err == nil {
+			log.Debug().Str("user", a.username).Msg("Renewed Proxmox ticket")
+			return a.ticket, a.csrf, nil
+		}
+		log.Debug().Str("user", a.username).Msg("Ticket renewal failed; logging in again")
+	}
+
+	if err := c.requestTicket(ctx, a.password); err != nil {
+		return "", "", err
+	}
+	log.Debug().Str("user", a.username).Msg("Obtained Proxmox ticket")
+	return a.ticket, a.csrf, nil
+}
+
+// requestTicket posts to /access/ticket; the caller must hold a.mu.
+func (c *Client) requestTicket(ctx context.Context, password string) error {
+	a := c.auth
+	form := url.Values{"username": {a.username}, "password": {password}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/access/ticket", strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("build proxmox login request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.proxmoxHTTP.Do(req)
+	if err != nil {
+		return fmt.Errorf("proxmox login request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var data struct {
+		Ticket string `json:"ticket"`
+		CSRF   string `json:"CSRFPreventionToken"`
+	}
+	if err := decodeProxmoxResponse(resp, &data); err != nil {
+		return fmt.Errorf("proxmox login as %s: %w", a.username, err)
+	}
+	if data.Ticket == "" {
+		return fmt.Errorf("proxmox login as %s: response missing ticket", a.username)
+	}
+
+	a.ticket, a.csrf, a.issued = data.Ticket, data.CSRF, time.Now()
+	return nil
+}
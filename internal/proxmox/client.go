@@ -3,7 +3,6 @@ package proxmox
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -15,6 +14,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/zerodi/cctl/config"
+	"github.com/zerodi/cctl/internal/httpx"
+
 	"github.com/rs/zerolog/log"
 )
 
@@ -22,8 +24,8 @@ const (
 	defaultTimeout            = 60 * time.Second
 	defaultISOStorage         = "local"
 	defaultSchematicFile      = ".schematic_id"
-	defaultTalosSchematicPath = "talos-factory-schematic.yaml"
-	defaultTemplateJSONPath   = "template.json"
+	defaultTalosSchematicPath = "config/talos-factory-schematic.yaml"
+	defaultTemplateJSONPath   = "config/template.json"
 )
 
 // Config describes the minimum information required to talk to the Proxmox and Talos APIs.
@@ -31,15 +33,24 @@ type Config struct {
 	URL                string        // Proxmox host without scheme
 	TokenID            string        // PVEAPIToken token ID
 	Secret             string        // PVEAPIToken secret
-	Node               string        // Proxmox node name
+	Username           string        // Optional user (e.g. root@pam) for ticket authentication; takes precedence over the token
+	Password           string        // Password for ticket authentication
+	Node               string        // Proxmox node name used for single-node calls
+	Nodes              []string      // Optional nodes to place images/templates on ("all" for every online member)
+	AllowedNodes       []string      // Optional nodes the workload cluster may use; default for and limit on Nodes
 	ISOStorage         string        // Proxmox storage target for ISO uploads
 	SchematicFile      string        // Path to cached schematic id file
 	TalosSchematicPath string        // Talos factory schematic YAML path
 	TemplateJSONPath   string        // VM template payload
 	SkipTLSVerify      bool          // Whether to skip TLS verification for Proxmox API calls
+	CAFile             string        // Optional PEM file with the Proxmox cluster CA
+	Fingerprint        string        // Optional pinned SHA-256 fingerprint of the Proxmox certificate
 	HTTPClient         *http.Client  // Optional custom HTTP client for Proxmox
 	FactoryClient      *http.Client  // Optional custom HTTP client for Talos factory
-	Timeout            time.Duration // Optional override for HTTP timeouts
+	Timeout            time.Duration // Optional override for the per-attempt wait for HTTP response headers
+	TaskTimeout        time.Duration // Optional override for how long to wait on Proxmox tasks
+	Retries            int           // Retries for transient failures of idempotent requests (0 disables)
+	RetryMaxElapsed    time.Duration // Optional cap on the total time spent retrying a request
 }
 
 // Client contains helpers for interacting with Proxmox and Talos factory APIs.
@@ -47,11 +58,15 @@ type Client struct {
 	baseURL            string
 	tokenID            string
 	secret             string
+	auth               *ticketAuth
 	node               string
+	nodes              []string
+	allowedNodes       []string
 	isoStorage         string
 	schematicFile      string
 	talosSchematicPath string
 	templateJSONPath   string
+	taskTimeout        time.Duration
 	proxmoxHTTP        *http.Client
 	factoryHTTP        *http.Client
 }
@@ -61,13 +76,25 @@ func New(cfg Config) (*Client, error) {
 	if cfg.URL == "" {
 		return nil, errors.New("proxmox URL is required")
 	}
-	if cfg.TokenID == "" {
-		return nil, errors.New("proxmox token ID is required")
+	var auth *ticketAuth
+	switch {
+	case cfg.Username != "":
+		if cfg.Password == "" {
+			return nil, errors.New("proxmox password is required for ticket authentication")
+		}
+		auth = &ticketAuth{username: cfg.Username, password: cfg.Password}
+	case cfg.TokenID != "":
+		if cfg.Secret == "" {
+			return nil, errors.New("proxmox token secret is required")
+		}
+	default:
+		return nil, errors.New("proxmox token ID or username is required")
 	}
-	if cfg.Secret == "" {
-		return nil, errors.New("proxmox token secret is required")
+	node := cfg.Node
+	if node == "" && len(cfg.Nodes) > 0 && cfg.Nodes[0] != allNodes {
+		node = cfg.Nodes[0]
 	}
-	if cfg.Node == "" {
+	if node == "" {
 		return nil, errors.New("proxmox node is required")
 	}
 
@@ -76,56 +103,78 @@ func New(cfg Config) (*Client, error) {
 		timeout = defaultTimeout
 	}
 
+	retry := httpx.RetryPolicy{MaxRetries: cfg.Retries, MaxElapsed: cfg.RetryMaxElapsed}
+
+	// The timeout bounds every attempt rather than the whole request: an http.Client timeout would
+	// also cover the retry backoff and cut retries off long before RetryMaxElapsed.
+	newTransport := func() *http.Transport {
+		tr := http.DefaultTransport.(*http.Transport).Clone()
+		tr.ResponseHeaderTimeout = timeout
+		return tr
+	}
+
 	proxmoxClient := cfg.HTTPClient
 	if proxmoxClient == nil {
-		tr := &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: cfg.SkipTLSVerify}, //nolint:gosec // CLI tool mirrors curl -k behaviour
+		tlsCfg, err := newTLSConfig(cfg.CAFile, cfg.Fingerprint, cfg.SkipTLSVerify)
+		if err != nil {
+			return nil, err
 		}
-		proxmoxClient = &http.Client{Timeout: timeout, Transport: tr}
+		tr := newTransport()
+		tr.TLSClientConfig = tlsCfg
+		proxmoxClient = &http.Client{Transport: httpx.NewRetryTransport(tr, retry)}
 	}
 
 	factoryClient := cfg.FactoryClient
 	if factoryClient == nil {
-		factoryClient = &http.Client{Timeout: timeout}
-	}
+		factoryClient = &http.Client{Transport: httpx.NewRetryTransport(newTransport(), retry)}
+	}
+
+	taskTimeout := cfg.TaskTimeout
+	if taskTimeout == 0 {
+		taskTimeout = defaultTaskTimeout
+	}
+
+	c := newLocalClient(cfg)
+	c.baseURL = fmt.Sprintf("https://%s/api2/json", apiAddress(cfg.URL))
+	c.tokenID = cfg.TokenID
+	c.secret = cfg.Secret
+	c.auth = auth
+	c.node = node
+	c.nodes = cfg.Nodes
+	c.allowedNodes = cfg.AllowedNodes
+	c.taskTimeout = taskTimeout
+	c.proxmoxHTTP = proxmoxClient
+	c.factoryHTTP = factoryClient
+	return c, nil
+}
 
-	schematicFile := cfg.SchematicFile
-	if schematicFile == "" {
-		schematicFile = defaultSchematicFile
+// newLocalClient returns a client that only knows the local file paths and the ISO storage,
+// enough for operations that do not talk to Proxmox.
+func newLocalClient(cfg Config) *Client {
+	c := &Client{
+		isoStorage:         cfg.ISOStorage,
+		schematicFile:      cfg.SchematicFile,
+		talosSchematicPath: cfg.TalosSchematicPath,
+		templateJSONPath:   cfg.TemplateJSONPath,
 	}
-
-	isoStorage := cfg.ISOStorage
-	if isoStorage == "" {
-		isoStorage = defaultISOStorage
+	if c.isoStorage == "" {
+		c.isoStorage = defaultISOStorage
 	}
-
-	talosSchematic := cfg.TalosSchematicPath
-	if talosSchematic == "" {
-		talosSchematic = defaultTalosSchematicPath
+	if c.schematicFile == "" {
+		c.schematicFile = defaultSchematicFile
 	}
-
-	templateJSON := cfg.TemplateJSONPath
-	if templateJSON == "" {
-		templateJSON = defaultTemplateJSONPath
+	if c.talosSchematicPath == "" {
+		c.talosSchematicPath = defaultTalosSchematicPath
 	}
-
-	return &Client{
-		baseURL:            fmt.Sprintf("https://%s:8006/api2/json", cfg.URL),
-		tokenID:            cfg.TokenID,
-		secret:             cfg.Secret,
-		node:               cfg.Node,
-		isoStorage:         isoStorage,
-		schematicFile:      schematicFile,
-		talosSchematicPath: talosSchematic,
-		templateJSONPath:   templateJSON,
-		proxmoxHTTP:        proxmoxClient,
-		factoryHTTP:        factoryClient,
-	}, nil
+	if c.templateJSONPath == "" {
+		c.templateJSONPath = defaultTemplateJSONPath
+	}
+	return c
 }
 
 // RefreshSchematic uploads the Talos factory schematic YAML and caches the returned ID locally.
 func (c *Client) RefreshSchematic(ctx context.Context) (string, error) {
-	data, err := os.ReadFile(c.talosSchematicPath)
+	data, err := config.ReadFile(c.talosSchematicPath)
 	if err != nil {
 		return "", fmt.Errorf("read schematic yaml: %w", err)
 	}
@@ -135,6 +184,8 @@ func (c *Client) RefreshSchematic(ctx context.Context) (string, error) {
 		return "", fmt.Errorf("build talos request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/x-yaml")
+	// Schematic IDs are content-addressed, so re-posting is safe; a nil value marks it idempotent without sending it.
+	req.Header["Idempotency-Key"] = nil
 
 	resp, err := c.factoryHTTP.Do(req)
 	if err != nil {
@@ -196,11 +247,19 @@ func (c *Client) EnsureSchematic(ctx context.Context) (string, error) {
 	return c.RefreshSchematic(ctx)
 }
 
-// GetTalosImage downloads the Talos ISO for the provided version and uploads it to Proxmox.
-func (c *Client) GetTalosImage(ctx context.Context, version string) error {
+// GetTalosImage transfers the Talos ISO for the provided version into Proxmox storage on every target node,
+// or once if the ISO storage is shared. By default each Proxmox node downloads the image itself;
+// ImageModeRelay downloads it locally once and uploads it to each node.
+func (c *Client) GetTalosImage(ctx context.Context, version string, opts ImageOptions) error {
 	if version == "" {
 		return errors.New("talos version is required")
 	}
+	if opts.Mode == "" {
+		opts.Mode = ImageModeDownloadURL
+	}
+	if opts.Mode != ImageModeDownloadURL && opts.Mode != ImageModeRelay {
+		return fmt.Errorf("unknown image mode %q", opts.Mode)
+	}
 
 	id, err := c.EnsureSchematic(ctx)
 	if err != nil {
@@ -209,91 +268,198 @@ func (c *Client) GetTalosImage(ctx context.Context, version string) error {
 
 	isoName := fmt.Sprintf("talos-%s-nocloud-amd64.iso", version)
 	url := fmt.Sprintf("https://factory.talos.dev/image/%s/v%s/nocloud-amd64.iso", id, version)
-	log.Info().
-		Str("schematicID", id).
-		Str("version", version).
-		Str("url", url).
-		Msg("Downloading Talos ISO")
 
-	if err := c.downloadToFile(ctx, url, isoName); err != nil {
-		return fmt.Errorf("download talos iso: %w", err)
+	nodes, err := c.storageTargets(ctx, c.isoStorage)
+	if err != nil {
+		return err
 	}
-	defer func() {
-		if removeErr := os.Remove(isoName); removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
-			log.Warn().Err(removeErr).Str("file", isoName).Msg("Failed to remove temporary ISO")
-		}
-	}()
 
-	log.Info().
-		Str("node", c.node).
-		Str("storage", c.isoStorage).
-		Str("file", isoName).
-		Msg("Uploading ISO to Proxmox")
-
-	if err := c.uploadISO(ctx, isoName); err != nil {
-		return fmt.Errorf("upload iso to proxmox: %w", err)
+	var (
+		errs    []error
+		pending []isoTarget
+	)
+	for _, node := range nodes {
+		nc := c.forNode(node)
+		existing, needed, err := nc.prepareISO(ctx, isoName, url, opts)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("node %s: %w", node, err))
+			continue
+		}
+		if needed {
+			pending = append(pending, isoTarget{client: nc, existing: existing})
+		}
+	}
+	if len(pending) == 0 {
+		return errors.Join(errs...)
+	}
+
+	switch opts.Mode {
+	case ImageModeDownloadURL:
+		for _, t := range pending {
+			nc := t.client
+			log.Info().
+				Str("schematicID", id).
+				Str("version", version).
+				Str("node", nc.node).
+				Str("storage", nc.isoStorage).
+				Str("url", url).
+				Msg("Importing Talos ISO via Proxmox download-url")
+
+			err := nc.replaceISO(ctx, isoName, t.existing, func(name string) error {
+				return nc.downloadURL(ctx, url, name, opts)
+			})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("node %s: import talos iso: %w", nc.node, err))
+				continue
+			}
+			log.Info().Str("version", version).Str("node", nc.node).Msg("Talos ISO imported successfully")
+		}
+	case ImageModeRelay:
+		errs = append(errs, c.relayImage(ctx, url, isoName, opts, pending)...)
 	}
 
-	log.Info().Str("version", version).Msg("Talos ISO uploaded successfully")
-	return nil
+	return errors.Join(errs...)
 }
 
-// CreateTemplate ensures no VM with the same VMID exists, creates a new VM from JSON payload, and converts it to a template.
-func (c *Client) CreateTemplate(ctx context.Context) error {
-	payload, err := os.ReadFile(c.templateJSONPath)
+// isoTarget is a node the ISO is transferred to, with the stored copy the transfer replaces (nil if none).
+type isoTarget struct {
+	client   *Client
+	existing *StorageContent
+}
+
+// prepareISO checks the node for an existing copy of the ISO. It reports whether the ISO needs to
+// be transferred and returns the stale copy the transfer replaces, which is left in place (see replaceISO).
+//
+// Proxmox cannot hash a stored volume, so with an expected checksum an existing copy is never
+// trusted: it is replaced by a transfer that verifies the checksum.
+func (c *Client) prepareISO(ctx context.Context, isoName, url string, opts ImageOptions) (*StorageContent, bool, error) {
+	existing, err := c.findISO(ctx, isoName)
 	if err != nil {
-		return fmt.Errorf("read template json: %w", err)
-	}
+		return nil, false, fmt.Errorf("check existing iso: %w", err)
+	}
+	if existing == nil {
+		return nil, true, nil
+	}
+	if opts.Checksum != "" && !opts.Force {
+		log.Info().
+			Str("node", c.node).
+			Str("volid", existing.VolID).
+			Msg("Stored ISO cannot be checked against --checksum; replacing")
+	}
+	if !opts.Force && opts.Checksum == "" && c.isoUpToDate(ctx, existing, url) {
+		log.Info().
+			Str("node", c.node).
+			Str("volid", existing.VolID).
+			Str("size", FormatBytes(existing.Size)).
+			Msg("Talos ISO already present; skipping transfer (use --force to replace)")
+		return nil, false, nil
+	}
+	return existing, true, nil
+}
 
-	var meta struct {
-		VMID json.Number `json:"vmid"`
-		Name string      `json:"name"`
+// replaceISO stores isoName on the node with transfer, which must verify the checksum if one is
+// expected. A stored copy (existing) is only removed once the new ISO has been transferred under a
+// staging name; Proxmox cannot rename volumes, so the ISO is then transferred again under its own
+// name and the staging copy removed. A failed transfer leaves the old ISO, or the verified staging
+// copy, on the node so templates can still be built.
+func (c *Client) replaceISO(ctx context.Context, isoName string, existing *StorageContent, transfer func(name string) error) error {
+	if existing == nil {
+		return transfer(isoName)
 	}
-	if err := json.Unmarshal(payload, &meta); err != nil {
-		return fmt.Errorf("parse template json: %w", err)
+
+	staging := stagingISOName(isoName)
+	if err := c.removeISO(ctx, staging); err != nil {
+		return fmt.Errorf("remove stale staging iso: %w", err)
 	}
-	if meta.VMID == "" {
-		return errors.New("template json missing vmid")
+	if err := transfer(staging); err != nil {
+		if rmErr := c.removeISO(ctx, staging); rmErr != nil {
+			log.Warn().Err(rmErr).Str("node", c.node).Str("file", staging).Msg("Failed to remove staging ISO")
+		}
+		return fmt.Errorf("%w (kept existing %s)", err, existing.VolID)
 	}
-	if meta.Name == "" {
-		return errors.New("template json missing name")
+	if err := c.DeleteStorageContent(ctx, c.isoStorage, existing.VolID); err != nil {
+		return fmt.Errorf("remove existing iso: %w", err)
 	}
-	vmid, err := meta.VMID.Int64()
-	if err != nil {
-		return fmt.Errorf("vmid is not a number: %w", err)
+	if err := transfer(isoName); err != nil {
+		return fmt.Errorf("%w (verified copy kept as %s:iso/%s)", err, c.isoStorage, staging)
 	}
+	return c.removeISO(ctx, staging)
+}
 
-	exists, err := c.vmExists(ctx, vmid)
-	if err != nil {
-		return fmt.Errorf("check vm exists: %w", err)
-	}
-	if exists {
-		log.Warn().Int64("vmid", vmid).Msg("Existing VM found; deleting before template creation")
-		if err := c.deleteVM(ctx, vmid); err != nil {
-			return fmt.Errorf("delete vm %d: %w", vmid, err)
-		}
-		if err := c.waitForVMDeletion(ctx, vmid, 90*time.Second); err != nil {
-			return fmt.Errorf("wait for vm deletion: %w", err)
-		}
+// stagingISOName is the file name a replacement ISO is transferred to before the old copy is removed.
+func stagingISOName(isoName string) string {
+	return strings.TrimSuffix(isoName, ".iso") + ".cctl-staging.iso"
+}
+
+// removeISO deletes the named ISO from the ISO storage if it is present.
+func (c *Client) removeISO(ctx context.Context, name string) error {
+	existing, err := c.findISO(ctx, name)
+	if err != nil || existing == nil {
+		return err
 	}
+	return c.DeleteStorageContent(ctx, c.isoStorage, existing.VolID)
+}
+
+// isoUpToDate compares the stored ISO against the size advertised by the image source.
+// Proxmox does not expose volume checksums, so a matching name is trusted when the size is unknown.
+func (c *Client) isoUpToDate(ctx context.Context, existing *StorageContent, url string) bool {
+	size := c.remoteSize(ctx, url)
+	if size < 0 {
+		log.Debug().Str("volid", existing.VolID).Msg("Remote ISO size unknown; trusting existing file name")
+		return true
+	}
+	if size != existing.Size {
+		log.Info().
+			Str("volid", existing.VolID).
+			Int64("storedSize", existing.Size).
+			Int64("remoteSize", size).
+			Msg("Stored ISO size differs from source; replacing")
+		return false
+	}
+	return true
+}
 
+// relayImage downloads the ISO once and uploads it to each target node.
+func (c *Client) relayImage(ctx context.Context, url, isoName string, opts ImageOptions, targets []isoTarget) []error {
 	log.Info().
-		Int64("vmid", vmid).
-		Str("name", meta.Name).
-		Msg("Creating Proxmox VM from template descriptor")
+		Str("file", isoName).
+		Str("url", url).
+		Msg("Downloading Talos ISO")
 
-	if err := c.createVM(ctx, payload); err != nil {
-		return fmt.Errorf("create vm: %w", err)
+	if err := c.downloadToFile(ctx, url, isoName); err != nil {
+		return []error{fmt.Errorf("download talos iso: %w", err)}
 	}
+	defer func() {
+		if removeErr := os.Remove(isoName); removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
+			log.Warn().Err(removeErr).Str("file", isoName).Msg("Failed to remove temporary ISO")
+		}
+	}()
 
-	time.Sleep(2 * time.Second)
-
-	if err := c.convertToTemplate(ctx, vmid); err != nil {
-		return fmt.Errorf("convert vm %d to template: %w", vmid, err)
+	if opts.Checksum != "" {
+		if err := verifyFileChecksum(isoName, opts.algorithm(), opts.Checksum); err != nil {
+			return []error{fmt.Errorf("verify talos iso: %w", err)}
+		}
 	}
 
-	log.Info().Int64("vmid", vmid).Str("name", meta.Name).Msg("Template created successfully")
-	return nil
+	var errs []error
+	for _, t := range targets {
+		nc := t.client
+		log.Info().
+			Str("node", nc.node).
+			Str("storage", nc.isoStorage).
+			Str("file", isoName).
+			Msg("Uploading ISO to Proxmox")
+
+		err := nc.replaceISO(ctx, isoName, t.existing, func(name string) error {
+			return nc.uploadISO(ctx, isoName, name)
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("node %s: upload iso to proxmox: %w", nc.node, err))
+			continue
+		}
+		log.Info().Str("node", nc.node).Str("file", isoName).Msg("Talos ISO uploaded successfully")
+	}
+	return errs
 }
 
 func (c *Client) readSchematicID() (string, error) {
@@ -331,116 +497,93 @@ func (c *Client) downloadToFile(ctx context.Context, url, filename string) error
 	}
 	defer out.Close()
 
-	if _, err := io.Copy(out, resp.Body); err != nil {
+	progress := newProgressReader(resp.Body, "Download", filepath.Base(filename), resp.ContentLength)
+	if _, err := io.Copy(out, progress); err != nil {
 		return fmt.Errorf("write iso file: %w", err)
 	}
+	progress.finish()
 	return nil
 }
 
-func (c *Client) uploadISO(ctx context.Context, isoName string) error {
-	file, err := os.Open(isoName)
+// uploadISO uploads the local file src to the ISO storage as name.
+func (c *Client) uploadISO(ctx context.Context, src, name string) error {
+	file, err := os.Open(src)
 	if err != nil {
 		return fmt.Errorf("open iso: %w", err)
 	}
 	defer file.Close()
 
-	var buf bytes.Buffer
-	writer := multipart.NewWriter(&buf)
-	if err := writer.WriteField("content", "iso"); err != nil {
-		return fmt.Errorf("write multipart field: %w", err)
-	}
-
-	part, err := writer.CreateFormFile("filename", filepath.Base(isoName))
+	info, err := file.Stat()
 	if err != nil {
-		return fmt.Errorf("create multipart file: %w", err)
-	}
-	if _, err := io.Copy(part, file); err != nil {
-		return fmt.Errorf("copy iso into multipart: %w", err)
-	}
-
-	if err := writer.Close(); err != nil {
-		return fmt.Errorf("close multipart writer: %w", err)
+		return fmt.Errorf("stat iso: %w", err)
 	}
 
-	path := fmt.Sprintf("/nodes/%s/storage/%s/upload", c.node, c.isoStorage)
-	req, err := c.newProxmoxRequest(ctx, http.MethodPost, path, &buf)
+	body, contentType, length, err := newMultipartUpload(file, name, info.Size())
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Content-Type", writer.FormDataContentType())
+	defer body.Close()
 
-	resp, err := c.proxmoxHTTP.Do(req)
+	path := fmt.Sprintf("/nodes/%s/storage/%s/upload", c.node, c.isoStorage)
+	req, err := c.newProxmoxRequest(ctx, http.MethodPost, path, body)
 	if err != nil {
-		return fmt.Errorf("upload iso request failed: %w", err)
+		return err
 	}
-	defer resp.Body.Close()
+	req.Header.Set("Content-Type", contentType)
+	req.ContentLength = length
 
-	return checkProxmoxResponse(resp)
+	return c.doTask(ctx, req, "upload iso")
 }
 
-func (c *Client) vmExists(ctx context.Context, vmid int64) (bool, error) {
-	path := fmt.Sprintf("/nodes/%s/qemu/%d/config", c.node, vmid)
-	req, err := c.newProxmoxRequest(ctx, http.MethodGet, path, nil)
-	if err != nil {
-		return false, err
-	}
-
-	resp, err := c.proxmoxHTTP.Do(req)
-	if err != nil {
-		return false, fmt.Errorf("check vm request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	switch resp.StatusCode {
-	case http.StatusOK:
-		return true, nil
-	case http.StatusNotFound:
-		return false, nil
-	default:
-		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
-		return false, fmt.Errorf("vm existence check returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+// newMultipartUpload streams the ISO as a multipart form through an io.Pipe.
+// The Content-Length is computed up front so Proxmox receives a non-chunked request.
+func newMultipartUpload(file io.Reader, name string, size int64) (io.ReadCloser, string, int64, error) {
+	var envelope bytes.Buffer
+	probe := multipart.NewWriter(&envelope)
+	if _, err := writeUploadHeader(probe, name); err != nil {
+		return nil, "", 0, err
 	}
-}
-
-func (c *Client) deleteVM(ctx context.Context, vmid int64) error {
-	path := fmt.Sprintf("/nodes/%s/qemu/%d", c.node, vmid)
-	req, err := c.newProxmoxRequest(ctx, http.MethodDelete, path, nil)
-	if err != nil {
-		return err
+	if err := probe.Close(); err != nil {
+		return nil, "", 0, fmt.Errorf("close multipart writer: %w", err)
 	}
+	length := int64(envelope.Len()) + size
 
-	resp, err := c.proxmoxHTTP.Do(req)
-	if err != nil {
-		return fmt.Errorf("delete vm request failed: %w", err)
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	if err := writer.SetBoundary(probe.Boundary()); err != nil {
+		return nil, "", 0, fmt.Errorf("set multipart boundary: %w", err)
 	}
-	defer resp.Body.Close()
 
-	return checkProxmoxResponse(resp)
-}
-
-func (c *Client) waitForVMDeletion(ctx context.Context, vmid int64, timeout time.Duration) error {
-	deadline := time.Now().Add(timeout)
-	for {
-		if time.Now().After(deadline) {
-			return fmt.Errorf("timed out waiting for vm %d deletion", vmid)
+	go func() {
+		progress := newProgressReader(file, "Upload", name, size)
+		part, err := writeUploadHeader(writer, name)
+		if err == nil {
+			if _, err = io.Copy(part, progress); err != nil {
+				err = fmt.Errorf("copy iso into multipart: %w", err)
+			}
 		}
-
-		exists, err := c.vmExists(ctx, vmid)
-		if err != nil {
-			return err
+		if err == nil {
+			err = writer.Close()
 		}
-		if !exists {
-			return nil
+		if err == nil {
+			progress.finish()
 		}
+		pw.CloseWithError(err)
+	}()
 
-		select {
-		case <-ctx.Done():
-			return fmt.Errorf("wait for deletion cancelled: %w", ctx.Err())
-		case <-time.After(time.Second):
-		}
-	}
+	return pr, writer.FormDataContentType(), length, nil
 }
 
+func writeUploadHeader(writer *multipart.Writer, name string) (io.Writer, error) {
+	if err := writer.WriteField("content", "iso"); err != nil {
+		return nil, fmt.Errorf("write multipart field: %w", err)
+	}
+	part, err := writer.CreateFormFile("filename", name)
+	if err != nil {
+		return nil, fmt.Errorf("create multipart file: %w", err)
+	}
+	return part, nil
+}
 func (c *Client) createVM(ctx context.Context, payload []byte) error {
 	path := fmt.Sprintf("/nodes/%s/qemu", c.node)
 	req, err := c.newProxmoxRequest(ctx, http.MethodPost, path, bytes.NewReader(payload))
@@ -449,13 +592,7 @@ func (c *Client) createVM(ctx context.Context, payload []byte) error {
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.proxmoxHTTP.Do(req)
-	if err != nil {
-		return fmt.Errorf("create vm request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	return checkProxmoxResponse(resp)
+	return c.doTask(ctx, req, "create vm")
 }
 
 func (c *Client) convertToTemplate(ctx context.Context, vmid int64) error {
@@ -465,13 +602,7 @@ func (c *Client) convertToTemplate(ctx context.Context, vmid int64) error {
 		return err
 	}
 
-	resp, err := c.proxmoxHTTP.Do(req)
-	if err != nil {
-		return fmt.Errorf("convert to template request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	return checkProxmoxResponse(resp)
+	return c.doTask(ctx, req, "convert to template")
 }
 
 func (c *Client) newProxmoxRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
@@ -479,7 +610,9 @@ func (c *Client) newProxmoxRequest(ctx context.Context, method, path string, bod
 	if err != nil {
 		return nil, fmt.Errorf("build proxmox request: %w", err)
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("PVEAPIToken=%s=%s", c.tokenID, c.secret))
+	if err := c.authorize(req); err != nil {
+		return nil, err
+	}
 	req.Header.Set("Accept", "application/json")
 	return req, nil
 }
@@ -0,0 +1,121 @@
+package proxmox
+
+import (
+	"context"
+	"crypto/md5"  //nolint:gosec // Proxmox accepts md5 checksums for downloads
+	"crypto/sha1" //nolint:gosec // Proxmox accepts sha1 checksums for downloads
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// ImageMode selects how an ISO reaches Proxmox storage.
+type ImageMode string
+
+const (
+	// ImageModeDownloadURL lets the Proxmox node fetch the image itself via the download-url API.
+	ImageModeDownloadURL ImageMode = "download-url"
+	// ImageModeRelay downloads the image locally and uploads it to Proxmox (for nodes without internet access).
+	ImageModeRelay ImageMode = "relay"
+)
+
+// ParseImageMode validates an image mode string; an empty value selects download-url.
+func ParseImageMode(s string) (ImageMode, error) {
+	switch ImageMode(strings.ToLower(strings.TrimSpace(s))) {
+	case "", ImageModeDownloadURL:
+		return ImageModeDownloadURL, nil
+	case ImageModeRelay:
+		return ImageModeRelay, nil
+	default:
+		return "", fmt.Errorf("unknown image mode %q (expected %s or %s)", s, ImageModeDownloadURL, ImageModeRelay)
+	}
+}
+
+// ImageOptions tunes how GetTalosImage transfers the ISO.
+type ImageOptions struct {
+	Mode              ImageMode
+	Checksum          string // Optional expected checksum of the ISO
+	ChecksumAlgorithm string // md5|sha1|sha224|sha256|sha384|sha512 (default sha256)
+	Force             bool   // Replace the ISO even if a matching file already exists
+}
+
+func (o ImageOptions) algorithm() string {
+	if o.ChecksumAlgorithm == "" {
+		return "sha256"
+	}
+	return strings.ToLower(o.ChecksumAlgorithm)
+}
+
+// downloadURL asks Proxmox to fetch the file from src into the ISO storage and waits for the task.
+func (c *Client) downloadURL(ctx context.Context, src, filename string, opts ImageOptions) error {
+	form := url.Values{
+		"content":  {"iso"},
+		"filename": {filename},
+		"url":      {src},
+	}
+	if opts.Checksum != "" {
+		if _, err := newChecksumHash(opts.algorithm()); err != nil {
+			return err
+		}
+		form.Set("checksum", opts.Checksum)
+		form.Set("checksum-algorithm", opts.algorithm())
+	}
+
+	path := fmt.Sprintf("/nodes/%s/storage/%s/download-url", c.node, c.isoStorage)
+	req, err := c.newProxmoxRequest(ctx, http.MethodPost, path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return c.doTask(ctx, req, "download url")
+}
+
+func verifyFileChecksum(path, algorithm, expected string) error {
+	h, err := newChecksumHash(algorithm)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("hash %s: %w", path, err)
+	}
+
+	got := hex.EncodeToString(h.Sum(nil))
+	if !strings.EqualFold(got, strings.TrimSpace(expected)) {
+		return fmt.Errorf("%s checksum mismatch for %s: got %s, want %s", algorithm, path, got, expected)
+	}
+	return nil
+}
+
+func newChecksumHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case "md5":
+		return md5.New(), nil //nolint:gosec // matches Proxmox checksum-algorithm
+	case "sha1":
+		return sha1.New(), nil //nolint:gosec // matches Proxmox checksum-algorithm
+	case "sha224":
+		return sha256.New224(), nil
+	case "sha256":
+		return sha256.New(), nil
+	case "sha384":
+		return sha512.New384(), nil
+	case "sha512":
+		return sha512.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported checksum algorithm %q", algorithm)
+	}
+}
@@ -0,0 +1,121 @@
+package proxmox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeISOStorage serves the storage content list and delete calls of one node's ISO storage.
+type fakeISOStorage struct {
+	mu      sync.Mutex
+	volumes []string // file names
+}
+
+func (f *fakeISOStorage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	const prefix = "/api2/json/nodes/pve/storage/local/content"
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == prefix:
+		items := make([]StorageContent, 0, len(f.volumes))
+		for _, v := range f.volumes {
+			items = append(items, StorageContent{VolID: "local:iso/" + v, Content: "iso"})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": items})
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, prefix+"/"):
+		volid, _ := url.PathUnescape(strings.TrimPrefix(r.URL.EscapedPath(), prefix+"/"))
+		f.volumes = slices.DeleteFunc(f.volumes, func(v string) bool { return "local:iso/"+v == volid })
+		_, _ = w.Write([]byte(`{"data":null}`))
+	default:
+		http.Error(w, "unexpected "+r.Method+" "+r.URL.Path, http.StatusNotImplemented)
+	}
+}
+
+func (f *fakeISOStorage) add(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.volumes = append(f.volumes, name)
+}
+
+func (f *fakeISOStorage) files() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := slices.Clone(f.volumes)
+	slices.Sort(out)
+	return out
+}
+
+func TestReplaceISO(t *testing.T) {
+	const (
+		iso     = "talos-1.11.2-nocloud-amd64.iso"
+		staging = "talos-1.11.2-nocloud-amd64.cctl-staging.iso"
+	)
+	errTransfer := errors.New("checksum mismatch")
+	tests := []struct {
+		name      string
+		stored    []string
+		replace   bool
+		failOn    string // transfer name that fails
+		want      []string
+		wantNames []string // transfers attempted
+		wantErr   bool
+	}{
+		{name: "new iso", want: []string{iso}, wantNames: []string{iso}},
+		{name: "new iso fails", failOn: iso, want: []string{}, wantNames: []string{iso}, wantErr: true},
+		{name: "replace", stored: []string{iso}, replace: true, want: []string{iso}, wantNames: []string{staging, iso}},
+		{name: "replace over stale staging", stored: []string{iso, staging}, replace: true, want: []string{iso}, wantNames: []string{staging, iso}},
+		{name: "staging transfer fails keeps old iso", stored: []string{iso}, replace: true, failOn: staging,
+			want: []string{iso}, wantNames: []string{staging}, wantErr: true},
+		{name: "final transfer fails keeps verified copy", stored: []string{iso}, replace: true, failOn: iso,
+			want: []string{staging}, wantNames: []string{staging, iso}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &fakeISOStorage{volumes: slices.Clone(tt.stored)}
+			srv := httptest.NewServer(storage)
+			defer srv.Close()
+
+			c := newLocalClient(Config{})
+			c.baseURL = srv.URL + "/api2/json"
+			c.node = "pve"
+			c.proxmoxHTTP = srv.Client()
+
+			var existing *StorageContent
+			if tt.replace {
+				existing = &StorageContent{VolID: "local:iso/" + iso}
+			}
+			var names []string
+			err := c.replaceISO(context.Background(), iso, existing, func(name string) error {
+				names = append(names, name)
+				if name == tt.failOn {
+					if name == staging {
+						storage.add(name) // a partial file left behind by the failed transfer
+					}
+					return errTransfer
+				}
+				storage.add(name)
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("replaceISO() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, errTransfer) {
+				t.Fatalf("replaceISO() error = %v, want it to wrap the transfer error", err)
+			}
+			if !slices.Equal(names, tt.wantNames) {
+				t.Fatalf("transfers = %v, want %v", names, tt.wantNames)
+			}
+			if got := storage.files(); !slices.Equal(got, tt.want) {
+				t.Fatalf("stored ISOs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package proxmox
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// allNodes selects every online cluster member when used as the only entry of Config.Nodes.
+const allNodes = "all"
+
+// NodeStatus describes a Proxmox cluster member as reported by /cluster/status.
+type NodeStatus struct {
+	Name   string `json:"name"`
+	ID     int    `json:"nodeid"`
+	IP     string `json:"ip"`
+	Online bool   `json:"online"`
+	Local  bool   `json:"local"`
+}
+
+// ClusterNodes discovers the members of the Proxmox cluster. A standalone node reports only itself.
+func (c *Client) ClusterNodes(ctx context.Context) ([]NodeStatus, error) {
+	var entries []struct {
+		Type   string `json:"type"`
+		Name   string `json:"name"`
+		ID     int    `json:"nodeid"`
+		IP     string `json:"ip"`
+		Online int    `json:"online"`
+		Local  int    `json:"local"`
+	}
+	if err := c.getProxmox(ctx, "/cluster/status", nil, &entries); err != nil {
+		return nil, fmt.Errorf("get cluster status: %w", err)
+	}
+
+	var nodes []NodeStatus
+	for _, e := range entries {
+		if e.Type != "node" {
+			continue
+		}
+		nodes = append(nodes, NodeStatus{
+			Name:   e.Name,
+			ID:     e.ID,
+			IP:     e.IP,
+			Online: e.Online == 1,
+			Local:  e.Local == 1,
+		})
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	return nodes, nil
+}
+
+// TargetNodes resolves the nodes that images and templates are placed on: the configured node list,
+// every online member for "all", the allowed nodes when no list is configured, or just the primary
+// node when neither is. With allowed nodes configured, other nodes are rejected ("all" skips them).
+func (c *Client) TargetNodes(ctx context.Context) ([]string, error) {
+	requested := c.nodes
+	if len(requested) == 0 {
+		requested = c.allowedNodes
+	}
+	if len(requested) == 0 {
+		return []string{c.node}, nil
+	}
+
+	members, err := c.ClusterNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	online := make(map[string]bool, len(members))
+	for _, m := range members {
+		online[m.Name] = m.Online
+	}
+
+	if len(requested) == 1 && requested[0] == allNodes {
+		var names []string
+		for _, m := range members {
+			switch {
+			case !c.allowed(m.Name):
+				log.Debug().Str("node", m.Name).Msg("Skipping Proxmox node outside the allowed nodes")
+			case !m.Online:
+				log.Warn().Str("node", m.Name).Msg("Skipping offline Proxmox node")
+			default:
+				names = append(names, m.Name)
+			}
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("no online Proxmox node among the allowed nodes (%s)", strings.Join(c.allowedNodes, ", "))
+		}
+		return names, nil
+	}
+
+	for _, n := range requested {
+		if !c.allowed(n) {
+			return nil, fmt.Errorf("node %s is not among the allowed nodes (%s)", n, strings.Join(c.allowedNodes, ", "))
+		}
+		isOnline, known := online[n]
+		if !known {
+			return nil, fmt.Errorf("node %s is not a member of the Proxmox cluster", n)
+		}
+		if !isOnline {
+			return nil, fmt.Errorf("node %s is offline", n)
+		}
+	}
+	return requested, nil
+}
+
+// allowed reports whether the node is among the allowed nodes; without any, every node is allowed.
+func (c *Client) allowed(node string) bool {
+	return len(c.allowedNodes) == 0 || slices.Contains(c.allowedNodes, node)
+}
+
+// storageTargets returns the nodes an operation on the storage must run on. Shared storage is
+// visible cluster-wide, so a single node suffices.
+func (c *Client) storageTargets(ctx context.Context, storage string) ([]string, error) {
+	nodes, err := c.TargetNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) <= 1 {
+		return nodes, nil
+	}
+
+	shared, err := c.forNode(nodes[0]).storageShared(ctx, storage)
+	if err != nil {
+		return nil, err
+	}
+	if shared {
+		log.Info().Str("storage", storage).Str("node", nodes[0]).Msg("Storage is shared; running once")
+		return nodes[:1], nil
+	}
+	return nodes, nil
+}
+
+func (c *Client) storageShared(ctx context.Context, storage string) (bool, error) {
+	var status struct {
+		Shared int `json:"shared"`
+	}
+	path := fmt.Sprintf("/nodes/%s/storage/%s/status", c.node, storage)
+	if err := c.getProxmox(ctx, path, nil, &status); err != nil {
+		return false, fmt.Errorf("get storage %s status on %s: %w", storage, c.node, err)
+	}
+	return status.Shared == 1, nil
+}
+
+// forNode returns a copy of the client whose node-scoped calls target the given node.
+func (c *Client) forNode(node string) *Client {
+	nc := *c
+	nc.node = node
+	return &nc
+}
@@ -0,0 +1,83 @@
+package proxmox
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const progressInterval = 5 * time.Second
+
+// progressReader wraps a reader and periodically logs transferred bytes, rate and ETA.
+type progressReader struct {
+	r       io.Reader
+	action  string
+	file    string
+	total   int64 // -1 when unknown
+	done    int64
+	started time.Time
+	logged  time.Time
+}
+
+func newProgressReader(r io.Reader, action, file string, total int64) *progressReader {
+	now := time.Now()
+	return &progressReader{r: r, action: action, file: file, total: total, started: now, logged: now}
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.done += int64(n)
+	if now := time.Now(); now.Sub(p.logged) >= progressInterval {
+		p.logged = now
+		p.report(false)
+	}
+	return n, err
+}
+
+// finish logs the final transfer summary.
+func (p *progressReader) finish() {
+	p.report(true)
+}
+
+func (p *progressReader) report(final bool) {
+	elapsed := time.Since(p.started)
+	rate := 0.0
+	if secs := elapsed.Seconds(); secs > 0 {
+		rate = float64(p.done) / secs
+	}
+
+	ev := log.Info().
+		Str("file", p.file).
+		Str("transferred", FormatBytes(p.done)).
+		Str("rate", FormatBytes(int64(rate))+"/s")
+	if p.total > 0 {
+		ev = ev.
+			Str("total", FormatBytes(p.total)).
+			Str("percent", fmt.Sprintf("%.1f%%", float64(p.done)*100/float64(p.total)))
+		if !final && rate > 0 {
+			eta := time.Duration(float64(p.total-p.done) / rate * float64(time.Second))
+			ev = ev.Dur("eta", eta.Round(time.Second))
+		}
+	}
+	if final {
+		ev.Dur("elapsed", elapsed.Round(time.Second)).Msgf("%s complete", p.action)
+		return
+	}
+	ev.Msgf("%s in progress", p.action)
+}
+
+// FormatBytes renders a byte count with binary units (e.g. 1.2 GiB).
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
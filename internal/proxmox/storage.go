@@ -0,0 +1,114 @@
+package proxmox
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// StorageContent describes a single volume returned by the storage content API.
+type StorageContent struct {
+	VolID   string `json:"volid"`
+	Content string `json:"content"`
+	Format  string `json:"format"`
+	Size    int64  `json:"size"`
+	CTime   int64  `json:"ctime"`
+}
+
+// FileName returns the file part of the volume ID (e.g. talos-1.11.2-nocloud-amd64.iso).
+func (s StorageContent) FileName() string {
+	volid := s.VolID
+	if i := strings.Index(volid, ":"); i >= 0 {
+		volid = volid[i+1:]
+	}
+	if i := strings.LastIndex(volid, "/"); i >= 0 {
+		volid = volid[i+1:]
+	}
+	return volid
+}
+
+// Created returns the creation time of the volume.
+func (s StorageContent) Created() time.Time {
+	return time.Unix(s.CTime, 0)
+}
+
+// ListStorageContent lists volumes on the given storage, optionally filtered by content type (iso, images, vztmpl, ...).
+// An empty storage selects the configured ISO storage.
+func (c *Client) ListStorageContent(ctx context.Context, storage, contentType string) ([]StorageContent, error) {
+	if storage == "" {
+		storage = c.isoStorage
+	}
+
+	query := url.Values{}
+	if contentType != "" {
+		query.Set("content", contentType)
+	}
+
+	var items []StorageContent
+	path := fmt.Sprintf("/nodes/%s/storage/%s/content", c.node, storage)
+	if err := c.getProxmox(ctx, path, query, &items); err != nil {
+		return nil, fmt.Errorf("list storage %s content: %w", storage, err)
+	}
+	return items, nil
+}
+
+// DeleteStorageContent removes a volume from storage. The volume may be a full volume ID
+// (local:iso/foo.iso) or a bare ISO file name, which is resolved against the storage.
+func (c *Client) DeleteStorageContent(ctx context.Context, storage, volume string) error {
+	if storage == "" {
+		storage = c.isoStorage
+	}
+	if volume == "" {
+		return fmt.Errorf("volume is required")
+	}
+	volid := volume
+	if !strings.Contains(volid, ":") {
+		volid = fmt.Sprintf("%s:iso/%s", storage, volume)
+	}
+
+	path := fmt.Sprintf("/nodes/%s/storage/%s/content/%s", c.node, storage, url.PathEscape(volid))
+	req, err := c.newProxmoxRequest(ctx, http.MethodDelete, path, nil)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("node", c.node).Str("volid", volid).Msg("Deleting storage content")
+	return c.doTask(ctx, req, "delete storage content")
+}
+
+// findISO returns the ISO with the given file name on the ISO storage, or nil if it is missing.
+func (c *Client) findISO(ctx context.Context, name string) (*StorageContent, error) {
+	items, err := c.ListStorageContent(ctx, c.isoStorage, "iso")
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		if items[i].FileName() == name {
+			return &items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// remoteSize returns the Content-Length reported for a HEAD request, or -1 when unknown.
+func (c *Client) remoteSize(ctx context.Context, src string) int64 {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, src, nil)
+	if err != nil {
+		return -1
+	}
+	resp, err := c.factoryHTTP.Do(req)
+	if err != nil {
+		log.Debug().Err(err).Str("url", src).Msg("HEAD request failed")
+		return -1
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return -1
+	}
+	return resp.ContentLength
+}
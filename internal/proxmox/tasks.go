@@ -0,0 +1,217 @@
+package proxmox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultTaskTimeout = 15 * time.Minute
+	taskPollInterval   = time.Second
+	taskLogLimit       = 50
+)
+
+// UPID is a parsed Proxmox task identifier (UPID:node:pid:pstart:starttime:type:id:user:).
+type UPID struct {
+	Raw  string
+	Node string
+	Type string
+	ID   string
+	User string
+}
+
+// String returns the raw UPID.
+func (u UPID) String() string { return u.Raw }
+
+// ParseUPID splits a Proxmox task identifier into its components.
+func ParseUPID(raw string) (UPID, error) {
+	raw = strings.TrimSpace(raw)
+	parts := strings.Split(raw, ":")
+	if len(parts) < 8 || parts[0] != "UPID" {
+		return UPID{}, fmt.Errorf("invalid proxmox task id %q", raw)
+	}
+	return UPID{
+		Raw:  raw,
+		Node: parts[1],
+		Type: parts[5],
+		ID:   parts[6],
+		User: parts[7],
+	}, nil
+}
+
+// TaskError is returned when a Proxmox task stops with a non-OK exit status.
+type TaskError struct {
+	UPID       UPID
+	ExitStatus string
+	Log        []string
+}
+
+func (e *TaskError) Error() string {
+	msg := fmt.Sprintf("proxmox task %s on %s failed: %s", e.UPID.Type, e.UPID.Node, e.ExitStatus)
+	if n := len(e.Log); n > 0 {
+		msg += ": " + e.Log[n-1]
+	}
+	return msg
+}
+
+// IsTaskError reports whether err wraps a failed Proxmox task.
+func IsTaskError(err error) bool {
+	var taskErr *TaskError
+	return errors.As(err, &taskErr)
+}
+
+type taskStatus struct {
+	Status     string `json:"status"`
+	ExitStatus string `json:"exitstatus"`
+}
+
+// WaitForTask polls the task status until it stops and returns a *TaskError if it did not finish with "OK".
+func (c *Client) WaitForTask(ctx context.Context, upid UPID) error {
+	ctx, cancel := context.WithTimeout(ctx, c.taskTimeout)
+	defer cancel()
+
+	path := fmt.Sprintf("/nodes/%s/tasks/%s/status", upid.Node, url.PathEscape(upid.Raw))
+	ticker := time.NewTicker(taskPollInterval)
+	defer ticker.Stop()
+
+	for {
+		var status taskStatus
+		if err := c.getProxmox(ctx, path, nil, &status); err != nil {
+			return fmt.Errorf("task %s status: %w", upid.Type, err)
+		}
+		log.Debug().Str("upid", upid.Raw).Str("status", status.Status).Msg("Polled Proxmox task")
+
+		if status.Status == "stopped" {
+			if status.ExitStatus == "OK" {
+				return nil
+			}
+			lines, err := c.taskLog(ctx, upid)
+			if err != nil {
+				log.Warn().Err(err).Str("upid", upid.Raw).Msg("Failed to fetch task log")
+			}
+			return &TaskError{UPID: upid, ExitStatus: status.ExitStatus, Log: lines}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("wait for task %s: %w", upid.Type, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Client) taskLog(ctx context.Context, upid UPID) ([]string, error) {
+	path := fmt.Sprintf("/nodes/%s/tasks/%s/log", upid.Node, url.PathEscape(upid.Raw))
+	query := url.Values{"limit": {fmt.Sprint(taskLogLimit)}}
+
+	var entries []struct {
+		N int    `json:"n"`
+		T string `json:"t"`
+	}
+	if err := c.getProxmox(ctx, path, query, &entries); err != nil {
+		return nil, err
+	}
+
+	lines := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.T != "" {
+			lines = append(lines, e.T)
+		}
+	}
+	return lines, nil
+}
+
+// doTask sends a mutating request, parses the UPID from the response and waits for the task to stop.
+// Endpoints that complete synchronously return no UPID and are treated as finished.
+func (c *Client) doTask(ctx context.Context, req *http.Request, what string) error {
+	resp, err := c.proxmoxHTTP.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", what, err)
+	}
+	defer resp.Body.Close()
+
+	var raw *string
+	if err := decodeProxmoxResponse(resp, &raw); err != nil {
+		return err
+	}
+	if raw == nil || *raw == "" {
+		return nil
+	}
+
+	upid, err := ParseUPID(*raw)
+	if err != nil {
+		return err
+	}
+	log.Debug().Str("upid", upid.Raw).Str("type", upid.Type).Msg("Waiting for Proxmox task")
+	return c.WaitForTask(ctx, upid)
+}
+
+func (c *Client) getProxmox(ctx context.Context, path string, query url.Values, out any) error {
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	req, err := c.newProxmoxRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.proxmoxHTTP.Do(req)
+	if err != nil {
+		return fmt.Errorf("proxmox request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return decodeProxmoxResponse(resp, out)
+}
+
+// sendProxmox sends a form-encoded request and decodes the response data into out (which may be nil).
+func (c *Client) sendProxmox(ctx context.Context, method, path string, form url.Values, out any) error {
+	var body io.Reader
+	if len(form) > 0 {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := c.newProxmoxRequest(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := c.proxmoxHTTP.Do(req)
+	if err != nil {
+		return fmt.Errorf("proxmox request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return decodeProxmoxResponse(resp, out)
+}
+
+func decodeProxmoxResponse(resp *http.Response, out any) error {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return checkProxmoxResponse(resp)
+	}
+
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("decode proxmox response for %s: %w", resp.Request.URL.Path, err)
+	}
+	if out == nil || len(envelope.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return fmt.Errorf("decode proxmox data for %s: %w", resp.Request.URL.Path, err)
+	}
+	return nil
+}
@@ -0,0 +1,346 @@
+package proxmox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"github.com/zerodi/cctl/config"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	templateTagPrefix = "cctl-"
+	defaultMinVMID    = 900
+	defaultMaxVMID    = 999
+)
+
+// TemplateValues are substituted into the template JSON descriptor, which is rendered as a Go text/template.
+// Well-known keys are talosVersion, schematicID, isoStorage, diskStorage, bridge and vmid; any other key
+// (e.g. from --set) is available to custom descriptors as well.
+type TemplateValues map[string]string
+
+// TemplateOptions controls how CreateTemplate places templates and protects existing VMs.
+type TemplateOptions struct {
+	Versioned bool  // Allocate a free VMID and name the template after the Talos version and schematic
+	MinVMID   int64 // Lower bound of the VMID range used in versioned mode
+	MaxVMID   int64 // Upper bound of the VMID range used in versioned mode
+	Force     bool  // Replace a VM at the target VMID that is not a template with the matching cctl tag
+}
+
+// TemplateInfo describes a template created or reused by CreateTemplate.
+type TemplateInfo struct {
+	VMID   int64  `json:"vmid"`
+	Name   string `json:"name"`
+	Node   string `json:"node"`
+	Tag    string `json:"tag"`
+	Reused bool   `json:"reused"`
+}
+
+// TemplateTag returns the Proxmox tag identifying a cctl template for the Talos version and schematic.
+func TemplateTag(talosVersion, schematicID string) string {
+	if talosVersion == "" {
+		return templateTagPrefix + "template"
+	}
+	tag := templateTagPrefix + "talos-" + strings.TrimPrefix(talosVersion, "v")
+	if schematicID != "" {
+		tag += "-" + shortSchematic(schematicID)
+	}
+	return strings.ToLower(tag)
+}
+
+// VersionedTemplateName returns a DNS-safe template name derived from the Talos version and schematic.
+func VersionedTemplateName(talosVersion, schematicID string) string {
+	name := "talos-" + strings.ReplaceAll(strings.TrimPrefix(talosVersion, "v"), ".", "-")
+	if schematicID != "" {
+		name += "-" + shortSchematic(schematicID)
+	}
+	return strings.ToLower(name)
+}
+
+// RenderTemplate renders the template JSON descriptor with the provided values without contacting
+// Proxmox; only the file paths and ISO storage of cfg are used. isoStorage and schematicID default to
+// the configured storage and the cached schematic ID.
+func RenderTemplate(cfg Config, values TemplateValues) ([]byte, error) {
+	c := newLocalClient(cfg)
+	data, err := c.templateData(values)
+	if err != nil {
+		return nil, err
+	}
+	return c.renderTemplate(data)
+}
+
+// TemplateResult reports the outcome of template placement on a single node.
+type TemplateResult struct {
+	Node     string        `json:"node"`
+	Template *TemplateInfo `json:"template,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// CreateTemplate renders the template JSON descriptor, creates a VM from it and converts it to a template.
+// The template is placed on every target node, or once when the disk storage is shared.
+//
+// In versioned mode the template gets a free VMID from the configured range and a name derived from the
+// Talos version and schematic; an existing template with the same cctl tag is reused. Otherwise the VMID
+// from the descriptor is used: a template there with the same cctl tag is reused, and any other VM is
+// only replaced when Force is set, since ProxmoxMachineTemplates may still clone from it.
+func (c *Client) CreateTemplate(ctx context.Context, values TemplateValues, opts TemplateOptions) ([]TemplateResult, error) {
+	data, err := c.templateData(values)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := c.renderTemplate(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []string
+	if storage := data["diskStorage"]; storage != "" {
+		nodes, err = c.storageTargets(ctx, storage)
+	} else {
+		nodes, err = c.TargetNodes(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) > 1 && !opts.Versioned {
+		return nil, fmt.Errorf("placing templates on %d nodes requires versioned templates (VMIDs are cluster-wide)", len(nodes))
+	}
+
+	results := make([]TemplateResult, 0, len(nodes))
+	var errs []error
+	for _, node := range nodes {
+		info, err := c.forNode(node).createTemplate(ctx, data, payload, opts, len(nodes) > 1)
+		result := TemplateResult{Node: node, Template: info}
+		if err != nil {
+			result.Error = err.Error()
+			errs = append(errs, fmt.Errorf("node %s: %w", node, err))
+		}
+		results = append(results, result)
+	}
+	return results, errors.Join(errs...)
+}
+
+// createTemplate places the rendered descriptor on the client node. With perNode set, only templates
+// on this node are considered for reuse.
+func (c *Client) createTemplate(ctx context.Context, data TemplateValues, payload []byte, opts TemplateOptions, perNode bool) (*TemplateInfo, error) {
+	spec := map[string]any{}
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	if err := dec.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("parse template json: %w", err)
+	}
+
+	tag := TemplateTag(data["talosVersion"], data["schematicID"])
+	name, _ := spec["name"].(string)
+	var (
+		vmid int64
+		err  error
+	)
+
+	if opts.Versioned {
+		name = VersionedTemplateName(data["talosVersion"], data["schematicID"])
+		vms, err := c.ListVMs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, vm := range vms {
+			if vm.IsTemplate() && vm.HasTag(tag) && (!perNode || vm.Node == c.node) {
+				log.Info().Int64("vmid", vm.VMID).Str("name", vm.Name).Str("tag", tag).Msg("Matching template already exists; reusing it")
+				return &TemplateInfo{VMID: vm.VMID, Name: vm.Name, Node: vm.Node, Tag: tag, Reused: true}, nil
+			}
+		}
+		if vmid, err = c.nextFreeVMID(ctx, vms, opts.MinVMID, opts.MaxVMID); err != nil {
+			return nil, err
+		}
+	} else {
+		raw, ok := spec["vmid"].(json.Number)
+		if !ok {
+			return nil, errors.New("template json missing vmid")
+		}
+		if vmid, err = raw.Int64(); err != nil {
+			return nil, fmt.Errorf("vmid is not a number: %w", err)
+		}
+
+		existing, err := c.FindVM(ctx, vmid)
+		if err != nil {
+			return nil, fmt.Errorf("check vm exists: %w", err)
+		}
+		if existing != nil {
+			if existing.IsTemplate() && existing.HasTag(tag) {
+				log.Info().Int64("vmid", vmid).Str("name", existing.Name).Str("tag", tag).Msg("Matching template already exists; reusing it")
+				return &TemplateInfo{VMID: vmid, Name: existing.Name, Node: existing.Node, Tag: tag, Reused: true}, nil
+			}
+			if err := checkReplaceable(existing, tag, opts.Force); err != nil {
+				return nil, err
+			}
+			log.Warn().Int64("vmid", vmid).Str("name", existing.Name).Str("node", existing.Node).Msg("Existing VM found; deleting before template creation")
+			if err := c.deleteVM(ctx, existing.Node, vmid); err != nil {
+				return nil, fmt.Errorf("delete vm %d: %w", vmid, err)
+			}
+		}
+	}
+	if name == "" {
+		return nil, errors.New("template json missing name")
+	}
+
+	spec["vmid"] = vmid
+	spec["name"] = name
+	spec["tags"] = appendTag(spec["tags"], tag)
+	if payload, err = json.Marshal(spec); err != nil {
+		return nil, fmt.Errorf("encode template json: %w", err)
+	}
+
+	log.Info().
+		Int64("vmid", vmid).
+		Str("name", name).
+		Str("node", c.node).
+		Str("tag", tag).
+		Msg("Creating Proxmox VM from template descriptor")
+
+	if err := c.createVM(ctx, payload); err != nil {
+		return nil, fmt.Errorf("create vm: %w", err)
+	}
+
+	if err := c.convertToTemplate(ctx, vmid); err != nil {
+		return nil, fmt.Errorf("convert vm %d to template: %w", vmid, err)
+	}
+
+	log.Info().Int64("vmid", vmid).Str("name", name).Str("node", c.node).Msg("Template created successfully")
+	return &TemplateInfo{VMID: vmid, Name: name, Node: c.node, Tag: tag}, nil
+}
+
+func (c *Client) templateData(values TemplateValues) (TemplateValues, error) {
+	data := make(TemplateValues, len(values)+2)
+	data["isoStorage"] = c.isoStorage
+	id, err := c.readSchematicID()
+	if err != nil {
+		return nil, err
+	}
+	data["schematicID"] = id
+	for k, v := range values {
+		// Unset values keep the defaults above; other keys stay present even when empty, so that
+		// the descriptor's required guards report them by name.
+		if v == "" && data[k] != "" {
+			continue
+		}
+		data[k] = v
+	}
+	return data, nil
+}
+
+func (c *Client) renderTemplate(data TemplateValues) ([]byte, error) {
+	raw, err := config.ReadFile(c.templateJSONPath)
+	if err != nil {
+		return nil, fmt.Errorf("read template json: %w", err)
+	}
+
+	tmpl, err := template.New(c.templateJSONPath).
+		Option("missingkey=error").
+		Funcs(template.FuncMap{"required": required}).
+		Parse(string(raw))
+	if err != nil {
+		return nil, fmt.Errorf("parse template json: %w", err)
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return nil, fmt.Errorf("render template json: %w", err)
+	}
+	if !json.Valid(out.Bytes()) {
+		return nil, errors.New("rendered template json is not valid JSON")
+	}
+	return out.Bytes(), nil
+}
+
+// nextFreeVMID asks /cluster/nextid for a VMID and falls back to scanning the range when the
+// suggestion lies outside of it.
+func (c *Client) nextFreeVMID(ctx context.Context, vms []VM, minID, maxID int64) (int64, error) {
+	if minID == 0 {
+		minID = defaultMinVMID
+	}
+	if maxID == 0 {
+		maxID = defaultMaxVMID
+	}
+	if minID > maxID {
+		return 0, fmt.Errorf("invalid VMID range %d-%d", minID, maxID)
+	}
+
+	var next json.Number
+	if err := c.getProxmox(ctx, "/cluster/nextid", nil, &next); err != nil {
+		return 0, fmt.Errorf("get next vmid: %w", err)
+	}
+	if id, err := next.Int64(); err == nil && id >= minID && id <= maxID {
+		return id, nil
+	}
+
+	used := make(map[int64]struct{}, len(vms))
+	for _, vm := range vms {
+		used[vm.VMID] = struct{}{}
+	}
+	for id := minID; id <= maxID; id++ {
+		if _, ok := used[id]; ok {
+			continue
+		}
+		// /cluster/nextid?vmid=N fails if the ID is taken (including by containers).
+		if err := c.getProxmox(ctx, "/cluster/nextid", url.Values{"vmid": {fmt.Sprint(id)}}, nil); err != nil {
+			log.Debug().Err(err).Int64("vmid", id).Msg("VMID not available")
+			continue
+		}
+		return id, nil
+	}
+	return 0, fmt.Errorf("no free VMID in range %d-%d", minID, maxID)
+}
+
+// checkReplaceable allows deleting the VM at a template's VMID only with force: anything but a
+// template with the exact cctl tag may still be in use, e.g. as the clone source of machines.
+func checkReplaceable(vm *VM, tag string, force bool) error {
+	var reason string
+	switch {
+	case !vm.IsTemplate():
+		reason = fmt.Sprintf("vm %d (%s) is not a template", vm.VMID, vm.Name)
+	case vm.HasTag(tag):
+		return nil
+	default:
+		reason = fmt.Sprintf("template %d (%s) does not carry tag %s", vm.VMID, vm.Name, tag)
+		for _, t := range vm.TagList() {
+			if strings.HasPrefix(t, templateTagPrefix) {
+				reason = fmt.Sprintf("template %d (%s) carries cctl tag %s, not %s", vm.VMID, vm.Name, t, tag)
+				break
+			}
+		}
+	}
+	if force {
+		log.Warn().Int64("vmid", vm.VMID).Msg(reason + "; replacing it because of --force")
+		return nil
+	}
+	return fmt.Errorf("%s; refusing to delete it (use --force or versioned templates)", reason)
+}
+
+func appendTag(existing any, tag string) string {
+	tags, _ := existing.(string)
+	if tags == "" {
+		return tag
+	}
+	return tags + ";" + tag
+}
+
+func shortSchematic(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+func required(name, value string) (string, error) {
+	if value == "" {
+		return "", fmt.Errorf("template value %q is required", name)
+	}
+	return value, nil
+}
@@ -0,0 +1,48 @@
+package proxmox
+
+import "testing"
+
+func TestCheckReplaceable(t *testing.T) {
+	const tag = "cctl-talos-1.11.2-376567988"
+	tests := []struct {
+		name    string
+		vm      VM
+		force   bool
+		wantErr bool
+	}{
+		{name: "matching template", vm: VM{VMID: 900, Template: 1, Tags: "cctl-talos-1.11.2-376567988"}},
+		{name: "matching tag among others", vm: VM{VMID: 900, Template: 1, Tags: "talos;cctl-talos-1.11.2-376567988"}},
+		{name: "untagged template", vm: VM{VMID: 900, Template: 1}, wantErr: true},
+		{name: "foreign tags only", vm: VM{VMID: 900, Template: 1, Tags: "legacy"}, wantErr: true},
+		{name: "other cctl tag", vm: VM{VMID: 900, Template: 1, Tags: "cctl-talos-1.10.0-376567988"}, wantErr: true},
+		{name: "running vm", vm: VM{VMID: 900, Tags: tag}, wantErr: true},
+		{name: "untagged template forced", vm: VM{VMID: 900, Template: 1}, force: true},
+		{name: "running vm forced", vm: VM{VMID: 900}, force: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkReplaceable(&tt.vm, tag, tt.force)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkReplaceable() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTemplateNames(t *testing.T) {
+	tests := []struct {
+		version, schematic string
+		wantTag, wantName  string
+	}{
+		{"1.11.2", "376567988ad370138ad8b2698212367b8edcb69b5fd68c80be1f2ec7d603b4ba", "cctl-talos-1.11.2-37656798", "talos-1-11-2-37656798"},
+		{"v1.12.0", "", "cctl-talos-1.12.0", "talos-1-12-0"},
+	}
+	for _, tt := range tests {
+		if got := TemplateTag(tt.version, tt.schematic); got != tt.wantTag {
+			t.Errorf("TemplateTag(%q, %q) = %q, want %q", tt.version, tt.schematic, got, tt.wantTag)
+		}
+		if got := VersionedTemplateName(tt.version, tt.schematic); got != tt.wantName {
+			t.Errorf("VersionedTemplateName(%q, %q) = %q, want %q", tt.version, tt.schematic, got, tt.wantName)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package proxmox
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+const apiPort = "8006"
+
+// newTLSConfig builds the TLS settings for the Proxmox API. A pinned fingerprint takes precedence over
+// a CA file; without either the system roots are used unless verification is skipped.
+func newTLSConfig(caFile, fingerprint string, skipVerify bool) (*tls.Config, error) {
+	if fingerprint != "" {
+		pinned, err := parseFingerprint(fingerprint)
+		if err != nil {
+			return nil, err
+		}
+		return &tls.Config{
+			// Chain and hostname checks are replaced by the fingerprint check below,
+			// matching how the Proxmox UI and Terraform provider pin self-signed certificates.
+			InsecureSkipVerify: true, //nolint:gosec // verified via VerifyPeerCertificate
+			VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+				if len(rawCerts) == 0 {
+					return errors.New("proxmox presented no certificate")
+				}
+				sum := sha256.Sum256(rawCerts[0])
+				if !bytes.Equal(sum[:], pinned) {
+					return fmt.Errorf("proxmox certificate fingerprint %s does not match pinned %s",
+						formatFingerprint(sum[:]), formatFingerprint(pinned))
+				}
+				return nil
+			},
+		}, nil
+	}
+
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("read proxmox CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+		return &tls.Config{RootCAs: pool}, nil
+	}
+
+	return &tls.Config{InsecureSkipVerify: skipVerify}, nil //nolint:gosec // CLI tool mirrors curl -k behaviour
+}
+
+// FetchCertificate connects to the Proxmox API without verification and returns the leaf certificate.
+func FetchCertificate(ctx context.Context, host string) (*x509.Certificate, error) {
+	if host == "" {
+		return nil, errors.New("proxmox URL is required")
+	}
+	dialer := &tls.Dialer{Config: &tls.Config{InsecureSkipVerify: true}} //nolint:gosec // only used to inspect the certificate
+	conn, err := dialer.DialContext(ctx, "tcp", apiAddress(host))
+	if err != nil {
+		return nil, fmt.Errorf("connect to proxmox: %w", err)
+	}
+	defer conn.Close()
+
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, errors.New("proxmox presented no certificate")
+	}
+	return certs[0], nil
+}
+
+// Fingerprint returns the colon-separated SHA-256 fingerprint of the certificate.
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return formatFingerprint(sum[:])
+}
+
+// APIURL returns the Proxmox API endpoint (https://host:8006) in the form CAPMOX expects for PROXMOX_URL.
+func APIURL(host string) string {
+	return "https://" + apiAddress(host)
+}
+
+// apiAddress turns the configured URL (a bare host, host:port or https://host[:port]) into host:port.
+func apiAddress(host string) string {
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	if i := strings.Index(host, "/"); i >= 0 {
+		host = host[:i]
+	}
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(strings.Trim(host, "[]"), apiPort)
+}
+
+func parseFingerprint(s string) ([]byte, error) {
+	clean := strings.NewReplacer(":", "", " ", "").Replace(strings.TrimSpace(s))
+	clean = strings.TrimPrefix(strings.ToLower(clean), "sha256")
+	raw, err := hex.DecodeString(clean)
+	if err != nil || len(raw) != sha256.Size {
+		return nil, fmt.Errorf("invalid SHA-256 fingerprint %q", s)
+	}
+	return raw, nil
+}
+
+func formatFingerprint(sum []byte) string {
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
@@ -0,0 +1,142 @@
+package proxmox
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// VM describes a QEMU guest as reported by /cluster/resources.
+type VM struct {
+	VMID     int64  `json:"vmid"`
+	Name     string `json:"name"`
+	Node     string `json:"node"`
+	Status   string `json:"status"`
+	Template int    `json:"template"`
+	Tags     string `json:"tags"`
+}
+
+// IsTemplate reports whether the guest has been converted into a template.
+func (v VM) IsTemplate() bool { return v.Template == 1 }
+
+// TagList splits the Proxmox tag string (separated by ';', ',' or spaces).
+func (v VM) TagList() []string {
+	return strings.FieldsFunc(v.Tags, func(r rune) bool {
+		return r == ';' || r == ',' || r == ' '
+	})
+}
+
+// HasTag reports whether the guest carries the given tag.
+func (v VM) HasTag(tag string) bool {
+	for _, t := range v.TagList() {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ListVMs returns all QEMU guests in the Proxmox cluster sorted by VMID.
+func (c *Client) ListVMs(ctx context.Context) ([]VM, error) {
+	var resources []struct {
+		VM
+		Type string `json:"type"`
+	}
+	if err := c.getProxmox(ctx, "/cluster/resources", url.Values{"type": {"vm"}}, &resources); err != nil {
+		return nil, fmt.Errorf("list cluster vms: %w", err)
+	}
+
+	vms := make([]VM, 0, len(resources))
+	for _, r := range resources {
+		if r.Type == "qemu" {
+			vms = append(vms, r.VM)
+		}
+	}
+	sort.Slice(vms, func(i, j int) bool { return vms[i].VMID < vms[j].VMID })
+	return vms, nil
+}
+
+// FindVM returns the guest with the given VMID, or nil if it does not exist.
+func (c *Client) FindVM(ctx context.Context, vmid int64) (*VM, error) {
+	vms, err := c.ListVMs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range vms {
+		if vms[i].VMID == vmid {
+			return &vms[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (c *Client) deleteVM(ctx context.Context, node string, vmid int64) error {
+	path := fmt.Sprintf("/nodes/%s/qemu/%d", node, vmid)
+	req, err := c.newProxmoxRequest(ctx, http.MethodDelete, path, nil)
+	if err != nil {
+		return err
+	}
+
+	return c.doTask(ctx, req, "delete vm")
+}
+
+// VMIPs returns the non-loopback addresses reported by the QEMU guest agent.
+func (c *Client) VMIPs(ctx context.Context, vm VM) ([]string, error) {
+	var resp struct {
+		Result []struct {
+			Name        string `json:"name"`
+			IPAddresses []struct {
+				Address string `json:"ip-address"`
+			} `json:"ip-addresses"`
+		} `json:"result"`
+	}
+	path := fmt.Sprintf("/nodes/%s/qemu/%d/agent/network-get-interfaces", vm.Node, vm.VMID)
+	if err := c.getProxmox(ctx, path, nil, &resp); err != nil {
+		return nil, fmt.Errorf("query guest agent of vm %d: %w", vm.VMID, err)
+	}
+
+	var ips []string
+	for _, iface := range resp.Result {
+		if iface.Name == "lo" {
+			continue
+		}
+		for _, addr := range iface.IPAddresses {
+			ip := net.ParseIP(addr.Address)
+			if ip == nil || ip.IsLoopback() || ip.IsLinkLocalUnicast() {
+				continue
+			}
+			ips = append(ips, addr.Address)
+		}
+	}
+	return ips, nil
+}
+
+// DeleteVM stops the guest if it is running and destroys it including unreferenced disks.
+func (c *Client) DeleteVM(ctx context.Context, vm VM) error {
+	if vm.Status == "running" {
+		log.Info().Int64("vmid", vm.VMID).Str("node", vm.Node).Msg("Stopping VM")
+		path := fmt.Sprintf("/nodes/%s/qemu/%d/status/stop", vm.Node, vm.VMID)
+		req, err := c.newProxmoxRequest(ctx, http.MethodPost, path, nil)
+		if err != nil {
+			return err
+		}
+		if err := c.doTask(ctx, req, "stop vm"); err != nil {
+			return fmt.Errorf("stop vm %d: %w", vm.VMID, err)
+		}
+	}
+
+	log.Info().Int64("vmid", vm.VMID).Str("name", vm.Name).Str("node", vm.Node).Msg("Deleting VM")
+	query := url.Values{"purge": {"1"}, "destroy-unreferenced-disks": {"1"}}
+	path := fmt.Sprintf("/nodes/%s/qemu/%d?%s", vm.Node, vm.VMID, query.Encode())
+	req, err := c.newProxmoxRequest(ctx, http.MethodDelete, path, nil)
+	if err != nil {
+		return err
+	}
+	return c.doTask(ctx, req, "delete vm")
+}